@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/kiefbc/pokedexcli/internal/httputil"
+)
+
+// apiError replaces the message of an error from a PokeAPI request with one that tells
+// the user what went wrong and what to do about it. The original error is still
+// reachable with errors.As, e.g. as a *httputil.ServerError.
+type apiError struct {
+	message string
+	err     error
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+// describeAPIError wraps the typed request errors from httputil in an apiError with a
+// friendly message. Other errors, such as cancellation, are returned unchanged.
+func describeAPIError(err error) error {
+	var (
+		notFoundErr    *httputil.NotFoundError
+		rateLimitedErr *httputil.RateLimitedError
+		serverErr      *httputil.ServerError
+		statusErr      *httputil.StatusError
+		timeoutErr     *httputil.TimeoutError
+		decodeErr      *httputil.DecodeError
+	)
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		return &apiError{"PokeAPI has nothing at " + notFoundErr.URL, err}
+	case errors.As(err, &rateLimitedErr):
+		return &apiError{"PokeAPI is limiting requests, wait a moment and try again", err}
+	case errors.As(err, &serverErr):
+		return &apiError{"PokeAPI is unavailable (" + serverErr.Status + "), try again later", err}
+	case errors.As(err, &statusErr):
+		return &apiError{"PokeAPI refused the request for " + statusErr.URL + " (" + statusErr.Status + ")", err}
+	case errors.As(err, &timeoutErr):
+		return &apiError{"PokeAPI did not respond in time, check your connection and try again", err}
+	case errors.As(err, &decodeErr):
+		return &apiError{"PokeAPI sent a response that could not be read for " + decodeErr.URL, err}
+	}
+	return err
+}
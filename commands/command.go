@@ -1,20 +1,88 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/kiefbc/pokedexcli/internal/httputil"
 	"github.com/kiefbc/pokedexcli/internal/pokecache"
+	"github.com/kiefbc/pokedexcli/internal/savefile"
 )
 
 // Remove the duplicated HTTP client - now using shared utility
 
+const (
+	// DefaultBaseURL is the public PokeAPI, used when Config.BaseURL is empty
+	DefaultBaseURL = "https://pokeapi.co/api/v2/"
+	// DefaultSpritesBaseURL is where the sprite URLs returned by PokeAPI point
+	DefaultSpritesBaseURL = "https://raw.githubusercontent.com/PokeAPI/sprites/master/"
+)
+
 type Config struct {
-	NextURL     string
-	PreviousURL string
-	Cache       *pokecache.Cache
-	Pokedex     map[string]Pokemon
+	NextURL        string
+	PreviousURL    string
+	BaseURL        string // PokeAPI root; empty uses DefaultBaseURL
+	SpritesBaseURL string // sprites repository mirror; empty keeps the URLs returned by the API
+	Cache          *pokecache.Cache
+	Pokedex        map[string]Pokemon
+	Store          *savefile.Store   // nil disables persistence
+	Slots          *savefile.Slots   // nil disables named save slots
+	BundlePath     string            // offline bundle written by 'bundle build'; empty disables bundles
+	Offline        bool              // responses come from the offline bundle instead of the network
+	Output         OutputFormat      // how results are printed; empty means FormatText
+	AutoCorrect    bool              // retry misspelt Pokemon and area names with the closest match
+	Aliases        map[string]string // alternative command names, see CommandAlias
+	Macros         map[string]string // named command sequences, see CommandMacro
+	ConfigPath     string            // config file that aliases and macros are saved to; empty keeps them for this session
+	Out            io.Writer         // command output; nil means os.Stdout
+	Err            io.Writer         // errors and warnings; nil means os.Stderr
+
+	nameIndex *nameIndex // location area and Pokemon names seen this session, see names()
+}
+
+// Stdout returns the writer command output goes to.
+func (cfg *Config) Stdout() io.Writer {
+	if cfg.Out == nil {
+		return os.Stdout
+	}
+	return cfg.Out
+}
+
+// Stderr returns the writer errors and warnings go to.
+func (cfg *Config) Stderr() io.Writer {
+	if cfg.Err == nil {
+		return os.Stderr
+	}
+	return cfg.Err
+}
+
+// apiURL returns the URL of an API resource path such as "pokemon/pikachu" under the configured base URL.
+func (cfg *Config) apiURL(path string) string {
+	base := cfg.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// spriteURL rewrites a sprite URL to the configured sprites mirror.
+// URLs outside the sprites repository, or all URLs when no mirror is set, are returned unchanged.
+func (cfg *Config) spriteURL(url string) string {
+	if cfg.SpritesBaseURL == "" {
+		return url
+	}
+
+	path, found := strings.CutPrefix(url, DefaultSpritesBaseURL)
+	if !found {
+		return url
+	}
+	return strings.TrimSuffix(cfg.SpritesBaseURL, "/") + "/" + path
 }
 
 type Pokemon struct {
@@ -32,10 +100,37 @@ type Pokemon struct {
 	SpriteOfficial string   `json:"sprite_official,omitempty"`
 }
 
+// Command categories, in the order help lists them.
+const (
+	categoryGeneral   = "General"
+	categoryExploring = "Exploring"
+	categoryPokemon   = "Pokemon"
+	categorySessions  = "Sessions"
+	categoryScripting = "Scripting"
+	categorySettings  = "Settings and data"
+)
+
+var categoryOrder = []string{categoryGeneral, categoryExploring, categoryPokemon, categorySessions, categoryScripting, categorySettings}
+
 type CliCommand struct {
 	Name        string
 	Description string
-	Callback    func(*Config, ...string) error
+	Usage       string    // usage line; empty builds one from Name and Args, see UsageLine
+	Args        []ArgSpec // the arguments Run checks before calling Callback
+	Examples    []string  // example invocations shown by 'help <command>'
+	Category    string    // the heading help lists the command under
+	Callback    func(context.Context, *Config, ...string) error
+}
+
+// ArgSpec describes one argument of a command. The same description is used for the
+// help text, to check the arguments before the command runs and for tab completion.
+type ArgSpec struct {
+	Name        string                                          // shown as <name>, or [name] when optional
+	Description string                                          // what to give, e.g. "a Pokemon name"
+	Optional    bool                                            // the argument may be left out
+	Repeated    bool                                            // the last argument may be given any number of times
+	Values      []string                                        // the only values accepted, if set
+	Complete    func(ctx context.Context, cfg *Config) []string // completions when Values is not set
 }
 
 // GetCommands returns a map of all available CLI commands.
@@ -46,49 +141,267 @@ func GetCommands() map[string]CliCommand {
 		"help": {
 			Name:        "help",
 			Description: "Displays a help message",
+			Args:        []ArgSpec{{Name: "command", Description: "a command name", Optional: true, Complete: commandNames}},
+			Examples:    []string{"help", "help catch"},
+			Category:    categoryGeneral,
 			Callback:    CommandHelp,
 		},
 		"exit": {
 			Name:        "exit",
 			Description: "Exit the Pokedex",
+			Examples:    []string{"exit"},
+			Category:    categoryGeneral,
 			Callback:    CommandExit,
 		},
 		"map": {
 			Name:        "map",
 			Description: "Get a list of area maps",
+			Examples:    []string{"map"},
+			Category:    categoryExploring,
 			Callback:    CommandGetMaps,
 		},
 		"mapb": {
 			Name:        "mapb",
 			Description: "Go back to previous list of maps",
+			Examples:    []string{"mapb"},
+			Category:    categoryExploring,
 			Callback:    CommandGetMapsBack,
 		},
 		"explore": {
 			Name:        "explore",
 			Description: "Explore a specific area map",
+			Args:        []ArgSpec{{Name: "area", Description: "a location area name", Complete: areaNames}},
+			Examples:    []string{"explore eterna-city-area"},
+			Category:    categoryExploring,
 			Callback:    CommandExploreMap,
 		},
 		"catch": {
 			Name:        "catch",
 			Description: "Catch a specific Pokemon",
+			Args:        []ArgSpec{{Name: "pokemon", Description: "a Pokemon name", Complete: pokemonNames}},
+			Examples:    []string{"catch pikachu"},
+			Category:    categoryPokemon,
 			Callback:    CommandCatchPokemon,
 		},
 		"inspect": {
 			Name:        "inspect",
 			Description: "View details of a caught Pokemon",
+			Args:        []ArgSpec{{Name: "pokemon", Description: "a Pokemon name", Complete: caughtPokemonNames}},
+			Examples:    []string{"inspect pikachu"},
+			Category:    categoryPokemon,
 			Callback:    CommandInspect,
 		},
 		"pokedex": {
 			Name:        "pokedex",
 			Description: "View all caught Pokemon",
+			Examples:    []string{"pokedex"},
+			Category:    categoryPokemon,
 			Callback:    CommandPokedex,
 		},
+		"save": {
+			Name:        "save",
+			Description: "Save the current session to a named slot",
+			Args:        []ArgSpec{{Name: "slot", Description: "a slot name", Complete: slotNames}},
+			Examples:    []string{"save johto-run"},
+			Category:    categorySessions,
+			Callback:    CommandSave,
+		},
+		"load": {
+			Name:        "load",
+			Description: "Load a session from a named slot",
+			Args:        []ArgSpec{{Name: "slot", Description: "a slot name", Complete: slotNames}},
+			Examples:    []string{"load johto-run"},
+			Category:    categorySessions,
+			Callback:    CommandLoad,
+		},
+		"slots": {
+			Name:        "slots",
+			Description: "List all save slots",
+			Examples:    []string{"slots"},
+			Category:    categorySessions,
+			Callback:    CommandSlots,
+		},
+		"delete-slot": {
+			Name:        "delete-slot",
+			Description: "Delete a named save slot",
+			Args:        []ArgSpec{{Name: "slot", Description: "a slot name", Complete: slotNames}},
+			Examples:    []string{"delete-slot johto-run"},
+			Category:    categorySessions,
+			Callback:    CommandDeleteSlot,
+		},
+		"format": {
+			Name:        "format",
+			Description: "Show or set the output format (text, json, yaml)",
+			Args: []ArgSpec{{Name: "format", Description: "an output format", Optional: true,
+				Values: []string{string(FormatText), string(FormatJSON), string(FormatYAML)}}},
+			Examples: []string{"format", "format json"},
+			Category: categorySettings,
+			Callback: CommandFormat,
+		},
+		"source": {
+			Name:        "source",
+			Description: "Run the commands in a script file",
+			Args:        []ArgSpec{{Name: "file", Description: "a file name"}},
+			Examples:    []string{"source hunt.pdx"},
+			Category:    categoryScripting,
+			Callback:    CommandSource,
+		},
+		"bundle": {
+			Name:        "bundle",
+			Description: "Show the offline bundle or build a new one (info, build [limit])",
+			Usage:       "bundle [info|build [limit]]",
+			Args: []ArgSpec{
+				{Name: "action", Description: "what to do", Optional: true, Values: []string{"info", "build"}},
+				{Name: "limit", Description: "how many areas and Pokemon to fetch when building", Optional: true},
+			},
+			Examples: []string{"bundle", "bundle build 50"},
+			Category: categorySettings,
+			Callback: CommandBundle,
+		},
+		"cache": {
+			Name:        "cache",
+			Description: "Show cache stats or manage it (stats, list, purge [prefix], ttl <duration>)",
+			Usage:       "cache [stats|list|purge [prefix]|ttl <duration>]",
+			Args: []ArgSpec{
+				{Name: "action", Description: "what to do", Optional: true, Values: []string{"stats", "list", "purge", "ttl"}},
+				{Name: "value", Description: "the URL prefix to purge or the new TTL, e.g. 10m", Optional: true},
+			},
+			Examples: []string{"cache", "cache purge https://pokeapi.co/api/v2/pokemon/", "cache ttl 10m"},
+			Category: categorySettings,
+			Callback: CommandCache,
+		},
+		"alias": {
+			Name:        "alias",
+			Description: "List aliases or define one (alias <name> <command> [args...])",
+			Usage:       "alias [<name> <command> [args...]]",
+			Args: []ArgSpec{
+				{Name: "name", Description: "the alias", Optional: true},
+				{Name: "command", Description: "the command it stands for", Optional: true, Complete: commandNames},
+				{Name: "args", Description: "arguments always passed to the command", Optional: true, Repeated: true},
+			},
+			Examples: []string{"alias", "alias c catch", "alias dex pokedex"},
+			Category: categoryScripting,
+			Callback: CommandAlias,
+		},
+		"macro": {
+			Name:        "macro",
+			Description: "List macros or define one (macro <name> = <command> $1; <command> $2...)",
+			Usage:       "macro [<name> = <command>; <command>...]",
+			Args: []ArgSpec{{Name: "definition", Description: "the macro name, = and its commands separated by ;",
+				Optional: true, Repeated: true}},
+			Examples: []string{"macro", "macro hunt = explore $1; catch $2"},
+			Category: categoryScripting,
+			Callback: CommandMacro,
+		},
+		"unalias": {
+			Name:        "unalias",
+			Description: "Remove an alias or macro",
+			Args:        []ArgSpec{{Name: "name", Description: "an alias or macro name", Complete: definitionNames}},
+			Examples:    []string{"unalias c"},
+			Category:    categoryScripting,
+			Callback:    CommandUnalias,
+		},
 	}
 }
 
-// GetResponse is a convenience wrapper for the shared HTTP utility
-func GetResponse[T any](url string, cache *pokecache.Cache) (T, error) {
-	return httputil.GetResponseWithDefault[T](url, cache)
+// UsageLine returns the command's usage, e.g. "catch <pokemon>". Unless Usage is set,
+// it is built from Args: optional arguments in brackets, a fixed set of values as a|b
+// and a repeated argument followed by "...".
+func (cmd CliCommand) UsageLine() string {
+	if cmd.Usage != "" {
+		return cmd.Usage
+	}
+
+	parts := []string{cmd.Name}
+	for _, arg := range cmd.Args {
+		name := arg.Name
+		if len(arg.Values) > 0 {
+			name = strings.Join(arg.Values, "|")
+		}
+		if arg.Repeated {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ErrInvalidArgs is wrapped by the errors Run returns for arguments that don't match the command's Args.
+var ErrInvalidArgs = errors.New("invalid arguments")
+
+// usageError describes arguments that don't match a command's Args.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func (e *usageError) Unwrap() error {
+	return ErrInvalidArgs
+}
+
+// checkArgs checks args against the command's Args: every required argument is given,
+// there are no more than it takes and each has one of the accepted Values.
+// The error wraps ErrInvalidArgs.
+func (cmd CliCommand) checkArgs(args []string) error {
+	for i, arg := range cmd.Args {
+		if i >= len(args) {
+			if !arg.Optional {
+				return &usageError{fmt.Sprintf("%s command requires %s (usage: %s)", cmd.Name, arg.Description, cmd.UsageLine())}
+			}
+			break
+		}
+		if len(arg.Values) > 0 && !slices.Contains(arg.Values, args[i]) {
+			return &usageError{fmt.Sprintf("invalid %s %q for %s (expected %s)", arg.Name, args[i], cmd.Name, joinOr(arg.Values))}
+		}
+	}
+
+	if len(args) > len(cmd.Args) && (len(cmd.Args) == 0 || !cmd.Args[len(cmd.Args)-1].Repeated) {
+		return &usageError{fmt.Sprintf("too many arguments for %s (usage: %s)", cmd.Name, cmd.UsageLine())}
+	}
+	return nil
+}
+
+// ErrUnknownCommand is returned by Run for a command name that isn't in GetCommands.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Run looks up the command called name in GetCommands and runs it with args.
+// The arguments are lowercased, except for commands that take file paths, and checked
+// against the command's Args first. Names that are not commands are looked up in
+// cfg.Aliases and then cfg.Macros, so an alias of source keeps its file name's case.
+// The REPL, scripts and one-shot invocations all dispatch through here.
+// Returns an error wrapping ErrUnknownCommand if there is no such command, or the command's own error.
+func Run(ctx context.Context, cfg *Config, name string, args ...string) error {
+	if cmd, exists := GetCommands()[name]; exists {
+		args = normalizeArgs(name, args)
+		if err := cmd.checkArgs(args); err != nil {
+			return err
+		}
+		return cmd.Callback(ctx, cfg, args...)
+	}
+	if expansion, exists := cfg.Aliases[name]; exists {
+		return runAlias(ctx, cfg, name, expansion, args)
+	}
+	if body, exists := cfg.Macros[name]; exists {
+		return runMacro(ctx, cfg, name, body, args)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+}
+
+// GetResponse is a convenience wrapper for the shared HTTP utility.
+// Request errors get friendly messages from describeAPIError.
+func GetResponse[T any](ctx context.Context, url string, cache *pokecache.Cache) (T, error) {
+	result, err := httputil.GetResponseWithDefault[T](ctx, url, cache)
+	if err != nil {
+		return result, describeAPIError(err)
+	}
+	return result, nil
 }
 
 // ValidatePokemonName validates Pokemon names for security across all commands.
@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kiefbc/pokedexcli/internal/appconfig"
+)
+
+// maxExpansionDepth limits how deeply aliases and macros may expand into other aliases
+// and macros, so one that refers to itself fails instead of recursing forever.
+const maxExpansionDepth = 8
+
+// expansionDepthKey is the context key holding the number of aliases and macros currently being expanded.
+type expansionDepthKey struct{}
+
+// macroArg matches a positional argument in a macro: $1, $2 and so on, or $@ for all of them.
+var macroArg = regexp.MustCompile(`\$([1-9][0-9]*|@)`)
+
+// runAlias runs the command an alias stands for, with args appended to any arguments
+// the alias already has: with "pk = catch pikachu", "pk" runs "catch pikachu".
+func runAlias(ctx context.Context, cfg *Config, name, expansion string, args []string) error {
+	ctx, err := expand(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	words := ParseLine(expansion)
+	if len(words) == 0 {
+		return fmt.Errorf("alias %s has no command", name)
+	}
+	return Run(ctx, cfg, words[0], append(words[1:], args...)...)
+}
+
+// runMacro runs the steps of a macro in order with its positional arguments filled in,
+// stopping at the first step that fails.
+func runMacro(ctx context.Context, cfg *Config, name, body string, args []string) error {
+	ctx, err := expand(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	steps, err := macroSteps(body, args)
+	if err != nil {
+		return fmt.Errorf("macro %s %w", name, err)
+	}
+
+	for i, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := Run(ctx, cfg, step[0], step[1:]...); err != nil {
+			return fmt.Errorf("%s step %d (%s): %w", name, i+1, step[0], err)
+		}
+	}
+	return nil
+}
+
+// expand returns ctx with the expansion depth increased, or an error if name would nest too deeply.
+func expand(ctx context.Context, name string) (context.Context, error) {
+	depth, _ := ctx.Value(expansionDepthKey{}).(int)
+	if depth >= maxExpansionDepth {
+		return ctx, fmt.Errorf("%s: aliases and macros nested too deeply (max %d)", name, maxExpansionDepth)
+	}
+	return context.WithValue(ctx, expansionDepthKey{}, depth+1), nil
+}
+
+// macroSteps splits a macro body into the words of each step, replacing $1, $2... with args
+// and $@ with all of them. Returns an error if the body uses more arguments than were given.
+func macroSteps(body string, args []string) ([][]string, error) {
+	needed := 0
+	for _, match := range macroArg.FindAllStringSubmatch(body, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			needed = max(needed, n)
+		}
+	}
+	if len(args) < needed {
+		return nil, fmt.Errorf("needs %d argument(s), got %d", needed, len(args))
+	}
+
+	var steps [][]string
+	for _, step := range strings.Split(body, ";") {
+		step = macroArg.ReplaceAllStringFunc(step, func(arg string) string {
+			if arg == "$@" {
+				return strings.Join(args, " ")
+			}
+			n, _ := strconv.Atoi(arg[1:])
+			return args[n-1]
+		})
+		if words := ParseLine(step); len(words) > 0 {
+			steps = append(steps, words)
+		}
+	}
+	return steps, nil
+}
+
+// CommandAlias lists the aliases, or defines a new one for a command and optionally
+// some of its arguments. Aliases are saved to the config file.
+//
+// Usage: alias [<name> <command> [args...]]
+// Example: alias c catch
+func CommandAlias(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		printDefinitions(cfg, "aliases", cfg.Aliases)
+		return nil
+	}
+	if len(args) == 1 {
+		return fmt.Errorf("alias command requires a name and a command, e.g. 'alias c catch'")
+	}
+
+	// Only the names are folded here: the arguments keep their case, so that file paths
+	// survive, and are folded by Run when the alias is used
+	name, expansion := strings.ToLower(args[0]), strings.Join(NormalizeArgs(args[1:]), " ")
+	if err := cfg.checkDefinition(name, expansion); err != nil {
+		return err
+	}
+
+	if cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]string)
+	}
+	cfg.Aliases[name] = expansion
+	delete(cfg.Macros, name)
+	if err := cfg.saveDefinitions(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "%s is now an alias for %s\n", name, expansion)
+	return nil
+}
+
+// CommandMacro lists the macros, or defines a new one: a sequence of commands separated
+// by semicolons, with $1, $2... standing for the macro's arguments and $@ for all of them.
+// Macros are saved to the config file.
+//
+// Usage: macro [<name> = <command>; <command>...]
+// Example: macro hunt = explore $1; catch $2
+func CommandMacro(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		printDefinitions(cfg, "macros", cfg.Macros)
+		return nil
+	}
+
+	name, body, found := strings.Cut(strings.Join(args, " "), "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	// As with aliases, the arguments of each step keep their case until the macro runs
+	var steps []string
+	for _, step := range strings.Split(body, ";") {
+		if step = strings.Join(NormalizeArgs(strings.Fields(step)), " "); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	if !found || len(steps) == 0 {
+		return fmt.Errorf("macro command requires a name and commands, e.g. 'macro hunt = explore $1; catch $2'")
+	}
+
+	if err := cfg.checkDefinition(name, steps...); err != nil {
+		return err
+	}
+
+	if cfg.Macros == nil {
+		cfg.Macros = make(map[string]string)
+	}
+	cfg.Macros[name] = strings.Join(steps, "; ")
+	delete(cfg.Aliases, name)
+	if err := cfg.saveDefinitions(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "%s now runs: %s\n", name, cfg.Macros[name])
+	return nil
+}
+
+// CommandUnalias removes an alias or macro and saves the change to the config file.
+//
+// Usage: unalias <name>
+// Example: unalias c
+func CommandUnalias(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("unalias command requires an alias or macro name")
+	}
+
+	name := args[0]
+	_, isAlias := cfg.Aliases[name]
+	_, isMacro := cfg.Macros[name]
+	if !isAlias && !isMacro {
+		return fmt.Errorf("no alias or macro named %q", name)
+	}
+
+	delete(cfg.Aliases, name)
+	delete(cfg.Macros, name)
+	if err := cfg.saveDefinitions(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "Removed %s\n", name)
+	return nil
+}
+
+// checkDefinition checks that name can be used for an alias or macro, and that each of
+// commands starts with a known command, alias or macro.
+func (cfg *Config) checkDefinition(name string, commands ...string) error {
+	if name == "" || strings.ContainsAny(name, " \t$;=#") {
+		return fmt.Errorf("invalid alias or macro name %q", name)
+	}
+	if _, exists := GetCommands()[name]; exists {
+		return fmt.Errorf("%q is already a command", name)
+	}
+
+	for _, command := range commands {
+		words := ParseLine(command)
+		if len(words) == 0 || !cfg.isCommand(words[0]) {
+			return fmt.Errorf("unknown command in %q", command)
+		}
+	}
+	return nil
+}
+
+// isCommand reports whether name is a command, alias or macro.
+func (cfg *Config) isCommand(name string) bool {
+	_, isCommand := GetCommands()[name]
+	_, isAlias := cfg.Aliases[name]
+	_, isMacro := cfg.Macros[name]
+	return isCommand || isAlias || isMacro
+}
+
+// saveDefinitions writes the aliases and macros to the config file, if there is one.
+func (cfg *Config) saveDefinitions() error {
+	if cfg.ConfigPath == "" {
+		return nil
+	}
+	return appconfig.Update(cfg.ConfigPath, func(settings *appconfig.Settings) {
+		settings.Aliases = cfg.Aliases
+		settings.Macros = cfg.Macros
+	})
+}
+
+// printDefinitions lists aliases or macros, kind being the plural used in the heading.
+func printDefinitions(cfg *Config, kind string, definitions map[string]string) {
+	if len(definitions) == 0 {
+		fmt.Fprintf(cfg.Stdout(), "No %s defined.\n", kind)
+		return
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "%s%s:\n", strings.ToUpper(kind[:1]), kind[1:])
+	for _, name := range sortedKeys(definitions) {
+		fmt.Fprintf(cfg.Stdout(), "  %s = %s\n", name, definitions[name])
+	}
+}
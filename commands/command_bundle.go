@@ -0,0 +1,340 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/kiefbc/pokedexcli/internal/bundle"
+	"github.com/kiefbc/pokedexcli/internal/httputil"
+)
+
+const (
+	// bundlePokemonListPath lists every Pokemon in large pages; it is only used to discover names
+	bundlePokemonListPath = "pokemon/?offset=0&limit=200"
+)
+
+// CommandBundle shows the offline bundle or builds a new one.
+//
+// Subcommands:
+// - info: where the bundle lives and how many responses it holds (the default)
+// - build [limit]: crawl PokeAPI into a fresh bundle. With a limit, only the first
+// limit location areas and Pokemon (plus every Pokemon found in those areas) are fetched.
+//
+// The bundle holds the location-area pages and areas, Pokemon, their species and the
+// sprites inspect displays, so that 'pokedexcli --offline' can run every command.
+//
+// Usage: bundle [info|build [limit]]
+// Example: bundle build 50
+func CommandBundle(ctx context.Context, cfg *Config, args ...string) error {
+	if cfg.BundlePath == "" {
+		return fmt.Errorf("offline bundles are not available")
+	}
+
+	subcommand := "info"
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+
+	switch subcommand {
+	case "info":
+		return bundleInfo(cfg)
+	case "build":
+		limit := 0
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid limit %q (expected a positive number)", args[1])
+			}
+			limit = n
+		}
+		return bundleBuild(ctx, cfg, limit)
+	default:
+		return fmt.Errorf("unknown bundle subcommand %q (expected info or build)", subcommand)
+	}
+}
+
+// bundleInfo prints the bundle location and size.
+func bundleInfo(cfg *Config) error {
+	reader, err := bundle.Open(cfg.BundlePath)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(cfg.Stdout(), "No offline bundle at %s. Use 'bundle build' to create one.\n", cfg.BundlePath)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	info, err := os.Stat(cfg.BundlePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "Offline bundle: %s\n", cfg.BundlePath)
+	fmt.Fprintf(cfg.Stdout(), "  Responses: %d (%s)\n", reader.Len(), formatBytes(info.Size()))
+	fmt.Fprintf(cfg.Stdout(), "  Built:     %s\n", info.ModTime().Local().Format("2006-01-02 15:04:05"))
+
+	return nil
+}
+
+// bundleBuild crawls the API into a new bundle, replacing the old one only once the crawl succeeds.
+func bundleBuild(ctx context.Context, cfg *Config, limit int) error {
+	if cfg.Offline {
+		return fmt.Errorf("cannot build a bundle in offline mode")
+	}
+
+	writer, err := bundle.Create(cfg.BundlePath)
+	if err != nil {
+		return err
+	}
+
+	crawler := &bundleCrawler{ctx: ctx, cfg: cfg, writer: writer, limit: limit, showProgress: isTerminal(cfg.Stderr())}
+	if err := crawler.crawl(); err != nil {
+		writer.Abort()
+		return fmt.Errorf("bundle build stopped: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "Saved %d responses to %s\n", writer.Len(), cfg.BundlePath)
+	if crawler.failed > 0 {
+		fmt.Fprintf(cfg.Stdout(), "%d resources could not be fetched and were skipped\n", crawler.failed)
+	}
+
+	return nil
+}
+
+// pokemonList is one page of the Pokemon list.
+type pokemonList struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+// bundleCrawler walks the API the same way the commands do, so that every URL a
+// command requests in offline mode is in the bundle.
+type bundleCrawler struct {
+	ctx    context.Context
+	cfg    *Config
+	writer *bundle.Writer
+	limit  int // 0 means everything
+	failed int // resources skipped because they could not be fetched
+
+	showProgress bool // progress lines go to stderr, and only when it is a terminal
+}
+
+// progress replaces the current progress line on stderr, if progress is shown.
+func (c *bundleCrawler) progress(format string, args ...any) {
+	if c.showProgress {
+		fmt.Fprintf(c.cfg.Stderr(), "\r"+format, args...)
+	}
+}
+
+// progressDone ends the current progress line.
+func (c *bundleCrawler) progressDone() {
+	if c.showProgress {
+		fmt.Fprintln(c.cfg.Stderr())
+	}
+}
+
+// isTerminal reports whether w is an interactive terminal rather than a pipe, file or buffer.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// crawl fetches the name indexes, location areas, then Pokemon with their species and sprites.
+func (c *bundleCrawler) crawl() error {
+	// Tab completion and "did you mean" suggestions read the full indexes, even with a limit
+	for _, path := range []string{areaIndexPath, pokemonIndexPath} {
+		if err := c.fetchJSON(c.cfg.apiURL(path), nil); err != nil {
+			return err
+		}
+	}
+
+	areas, err := c.crawlAreaPages()
+	if err != nil {
+		return err
+	}
+
+	pokemonNames := make(map[string]bool)
+	for i, area := range areas {
+		c.progress("Fetching location areas %d/%d...", i+1, len(areas))
+		var locationArea LocationArea
+		if err := c.fetchJSON(c.cfg.apiURL(explorePath+area), &locationArea); err != nil {
+			return err
+		}
+		for _, encounter := range locationArea.PokemonEncounters {
+			pokemonNames[encounter.Pokemon.Name] = true
+		}
+	}
+	c.progressDone()
+
+	listed, err := c.listPokemon()
+	if err != nil {
+		return err
+	}
+	for _, name := range listed {
+		pokemonNames[name] = true
+	}
+
+	names := make([]string, 0, len(pokemonNames))
+	for name := range pokemonNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		c.progress("Fetching Pokemon %d/%d...", i+1, len(names))
+		if err := c.crawlPokemon(name); err != nil {
+			return err
+		}
+	}
+	c.progressDone()
+
+	return nil
+}
+
+// crawlAreaPages stores every page of the location-area list that map and mapb can reach
+// and returns the area names, up to the limit.
+func (c *bundleCrawler) crawlAreaPages() ([]string, error) {
+	var names []string
+
+	url := c.cfg.apiURL(mapPath)
+	for url != "" {
+		var page AreaMaps
+		if err := c.fetchJSON(url, &page); err != nil {
+			return nil, err
+		}
+
+		// mapb requests the API's previous link, which differs from the URL of the first page
+		if previous, ok := page.Previous.(string); ok && previous != "" && !c.writer.Has(previous) {
+			if err := c.fetchJSON(previous, nil); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, result := range page.Results {
+			names = append(names, result.Name)
+		}
+		if c.limit > 0 && len(names) >= c.limit {
+			return names[:c.limit], nil
+		}
+
+		url = page.Next
+	}
+
+	return names, nil
+}
+
+// listPokemon returns the names of Pokemon in the API's list, up to the limit.
+// The list pages themselves are not stored since no command requests them; the single
+// page index that completion uses is stored by crawl.
+func (c *bundleCrawler) listPokemon() ([]string, error) {
+	var names []string
+
+	url := c.cfg.apiURL(bundlePokemonListPath)
+	for url != "" {
+		page, err := GetResponse[pokemonList](c.ctx, url, c.cfg.Cache)
+		if err != nil {
+			if c.ctx.Err() != nil {
+				return nil, c.ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to list Pokemon: %w", err)
+		}
+
+		for _, result := range page.Results {
+			names = append(names, result.Name)
+		}
+		if c.limit > 0 && len(names) >= c.limit {
+			return names[:c.limit], nil
+		}
+		url = page.Next
+	}
+
+	return names, nil
+}
+
+// crawlPokemon stores a Pokemon, its species and the sprites that inspect downloads.
+func (c *bundleCrawler) crawlPokemon(name string) error {
+	var pokemon CatchPokemon
+	if err := c.fetchJSON(c.cfg.apiURL(catchPath+name), &pokemon); err != nil {
+		return err
+	}
+	if pokemon.Name == "" {
+		return nil // skipped
+	}
+
+	if pokemon.Species.URL != "" {
+		if err := c.fetchJSON(pokemon.Species.URL, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, spriteURL := range []string{pokemon.Sprites.Other.OfficialArtwork.FrontDefault, pokemon.Sprites.FrontDefault} {
+		if spriteURL == "" {
+			continue
+		}
+		if err := c.fetchSprite(c.cfg.spriteURL(spriteURL)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetchJSON fetches url through GetResponse, stores the raw response in the bundle and,
+// if v is not nil, decodes it into v. Resources that fail to download are counted and
+// skipped; only cancellation and bundle write errors stop the crawl.
+func (c *bundleCrawler) fetchJSON(url string, v any) error {
+	raw, err := GetResponse[json.RawMessage](c.ctx, url, c.cfg.Cache)
+	if err != nil {
+		if c.ctx.Err() != nil {
+			return c.ctx.Err()
+		}
+		c.failed++
+		return nil
+	}
+
+	if err := c.writer.Add(url, raw); err != nil {
+		return err
+	}
+	if v != nil {
+		if err := json.Unmarshal(raw, v); err != nil {
+			c.failed++
+		}
+	}
+
+	return nil
+}
+
+// fetchSprite downloads a sprite and stores it in the bundle.
+func (c *bundleCrawler) fetchSprite(url string) error {
+	if c.writer.Has(url) {
+		return nil
+	}
+
+	data, err := httputil.Fetch(c.ctx, url, httputil.NewDefaultClient())
+	if err != nil {
+		if c.ctx.Err() != nil {
+			return c.ctx.Err()
+		}
+		c.failed++
+		return nil
+	}
+
+	return c.writer.Add(url, data)
+}
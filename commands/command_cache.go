@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kiefbc/pokedexcli/internal/sprites"
+)
+
+// CommandCache inspects and manages the API response cache and the sprite cache.
+//
+// Subcommands:
+// - stats: hit/stale/miss/expiration/eviction counters and memory, disk and sprite usage
+// - list: every cached API response with its size and age
+// - purge [prefix]: remove cached responses whose URL starts with prefix,
+// or everything including sprites when no prefix is given
+// - ttl <duration>: change how long responses stay fresh, e.g. "ttl 10m"
+//
+// Usage: cache [stats|list|purge [prefix]|ttl <duration>]
+// Example: cache purge https://pokeapi.co/api/v2/pokemon/
+func CommandCache(ctx context.Context, cfg *Config, args ...string) error {
+	if cfg.Cache == nil {
+		return fmt.Errorf("cache is not available")
+	}
+
+	subcommand := "stats"
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+
+	switch subcommand {
+	case "stats":
+		return cacheStats(cfg)
+	case "list":
+		return cacheList(cfg)
+	case "purge":
+		prefix := ""
+		if len(args) > 1 {
+			prefix = args[1]
+		}
+		return cachePurge(cfg, prefix)
+	case "ttl":
+		if len(args) < 2 {
+			fmt.Fprintf(cfg.Stdout(), "Cache TTL: %s\n", cfg.Cache.TTL())
+			return nil
+		}
+		return cacheSetTTL(cfg, args[1])
+	default:
+		return fmt.Errorf("unknown cache subcommand %q (expected stats, list, purge or ttl)", subcommand)
+	}
+}
+
+// cacheStats prints the cache counters and the memory, disk and sprite usage.
+func cacheStats(cfg *Config) error {
+	stats := cfg.Cache.Stats()
+
+	fmt.Fprintln(cfg.Stdout(), "API cache:")
+	fmt.Fprintf(cfg.Stdout(), "  Entries:     %d (%s in memory)\n", stats.Entries, formatBytes(int64(stats.Bytes)))
+	fmt.Fprintf(cfg.Stdout(), "  Hits:        %d\n", stats.Hits)
+	fmt.Fprintf(cfg.Stdout(), "  Stale hits:  %d\n", stats.StaleHits)
+	fmt.Fprintf(cfg.Stdout(), "  Misses:      %d\n", stats.Misses)
+	fmt.Fprintf(cfg.Stdout(), "  Expirations: %d\n", stats.Expirations)
+	fmt.Fprintf(cfg.Stdout(), "  Evictions:   %d\n", stats.Evictions)
+	fmt.Fprintf(cfg.Stdout(), "  TTL:         %s (reaped every %s)\n", stats.TTL, stats.ReapInterval)
+	if diskDir := cfg.Cache.DiskDir(); diskDir != "" {
+		fmt.Fprintf(cfg.Stdout(), "  Disk:        %d entries (%s) in %s\n", stats.DiskEntries, formatBytes(stats.DiskBytes), diskDir)
+	}
+
+	fmt.Fprintln(cfg.Stdout(), "Sprite cache:")
+	count, size, err := sprites.Usage()
+	if err != nil {
+		fmt.Fprintf(cfg.Stdout(), "  unavailable: %v\n", err)
+		return nil
+	}
+	spriteDir, _ := sprites.CacheDir()
+	fmt.Fprintf(cfg.Stdout(), "  Sprites:     %d (%s) in %s\n", count, formatBytes(size), spriteDir)
+
+	return nil
+}
+
+// cacheList prints every in-memory cache entry with its size and age.
+func cacheList(cfg *Config) error {
+	entries := cfg.Cache.Entries()
+	if len(entries) == 0 {
+		fmt.Fprintln(cfg.Stdout(), "The cache is empty.")
+		return nil
+	}
+
+	for _, entry := range entries {
+		fmt.Fprintf(cfg.Stdout(), "  %-10s %-8s %s\n", formatBytes(int64(entry.Size)), entry.Age.Truncate(time.Second), entry.Key)
+	}
+	fmt.Fprintf(cfg.Stdout(), "%d entries\n", len(entries))
+
+	return nil
+}
+
+// cachePurge removes cached responses matching prefix. Sprites are only
+// purged when no prefix is given, since they are not keyed by API URL.
+func cachePurge(cfg *Config, prefix string) error {
+	removed := cfg.Cache.Purge(prefix)
+	fmt.Fprintf(cfg.Stdout(), "Purged %d cached responses\n", removed)
+
+	if prefix != "" {
+		return nil
+	}
+
+	spritesRemoved, err := sprites.Purge()
+	if err != nil {
+		return fmt.Errorf("failed to purge sprites: %w", err)
+	}
+	fmt.Fprintf(cfg.Stdout(), "Purged %d cached sprites\n", spritesRemoved)
+
+	return nil
+}
+
+// cacheSetTTL parses value as a duration and applies it to the cache.
+func cacheSetTTL(cfg *Config, value string) error {
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (examples: 30s, 10m, 1h)", value)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("cache TTL must be positive")
+	}
+
+	cfg.Cache.SetTTL(ttl)
+	fmt.Fprintf(cfg.Stdout(), "Cache TTL set to %s\n", ttl)
+
+	return nil
+}
+
+// formatBytes renders a byte count using binary units, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
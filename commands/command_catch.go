@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -292,7 +293,7 @@ type CatchPokemon struct {
 }
 
 const (
-	catchBaseURL = "https://pokeapi.co/api/v2/pokemon/"
+	catchPath = "pokemon/"
 )
 
 // CommandCatchPokemon attempts to catch a Pokemon with realistic catch rates.
@@ -309,7 +310,7 @@ const (
 //
 // Usage: catch <pokemon_name>
 // Example: catch pikachu
-func CommandCatchPokemon(cfg *Config, args ...string) error {
+func CommandCatchPokemon(ctx context.Context, cfg *Config, args ...string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("catch command requires a Pokemon name")
 	}
@@ -320,17 +321,22 @@ func CommandCatchPokemon(cfg *Config, args ...string) error {
 	}
 
 	pokemonName := strings.ToLower(args[0])
-	url := fmt.Sprintf("%s%s", catchBaseURL, pokemonName)
-	fmt.Printf("Throwing a Pokeball at %s...", pokemonName)
+	if !cfg.structured() {
+		fmt.Fprintf(cfg.Stdout(), "Throwing a Pokeball at %s...", pokemonName)
+	}
 
-	caughtPokemon, err := GetResponse[CatchPokemon](url, cfg.Cache)
+	caughtPokemon, pokemonName, err := lookupName[CatchPokemon](ctx, cfg, "Pokemon", catchPath, pokemonName, cfg.allPokemon)
 	if err != nil {
 		return fmt.Errorf("failed to catch %s: %w", pokemonName, err)
 	}
+	cfg.names().addPokemon(pokemonName)
 
 	// Check if Pokemon is already caught
 	if _, exists := cfg.Pokedex[pokemonName]; exists {
-		fmt.Printf("\n%s is already in your Pokedex!\n", pokemonName)
+		if cfg.structured() {
+			return writeResult(cfg, CatchResult{Name: pokemonName, AlreadyCaught: true})
+		}
+		fmt.Fprintf(cfg.Stdout(), "\n%s is already in your Pokedex!\n", pokemonName)
 		return nil
 	}
 
@@ -375,10 +381,22 @@ func CommandCatchPokemon(cfg *Config, args ...string) error {
 		}
 
 		cfg.Pokedex[pokemonName] = pokemon
-		fmt.Printf("\n%s was caught!\n", pokemonName)
-		fmt.Printf("You may now inspect it with the inspect command.\n")
+		if cfg.structured() {
+			if err := writeResult(cfg, CatchResult{Name: pokemonName, Caught: true, Pokemon: &pokemon}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(cfg.Stdout(), "\n%s was caught!\n", pokemonName)
+			fmt.Fprintf(cfg.Stdout(), "You may now inspect it with the inspect command.\n")
+		}
+
+		if err := SavePokedex(cfg); err != nil {
+			return fmt.Errorf("%s was caught but could not be saved: %w", pokemonName, err)
+		}
+	} else if cfg.structured() {
+		return writeResult(cfg, CatchResult{Name: pokemonName})
 	} else {
-		fmt.Printf("\n%s escaped!\n", pokemonName)
+		fmt.Fprintf(cfg.Stdout(), "\n%s escaped!\n", pokemonName)
 	}
 
 	return nil
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 )
@@ -30,10 +31,18 @@ func SetExiter(e Exiter) {
 }
 
 // CommandExit handles the exit command by displaying a goodbye message and terminating the application.
-// It prints a farewell message and calls the configured exiter with status code 0.
+// It prints a farewell message, shuts down all subsystems and calls the configured exiter.
+// The exit status is 0, or 1 if the shutdown failed (for example the Pokedex could not be saved).
 // Returns nil, though the function typically terminates the program before returning.
-func CommandExit(cfg *Config, args ...string) error {
-	fmt.Println("Closing the Pokedex... Goodbye!")
+func CommandExit(ctx context.Context, cfg *Config, args ...string) error {
+	fmt.Fprintln(cfg.Stdout(), "Closing the Pokedex... Goodbye!")
+
+	if err := Shutdown(cfg); err != nil {
+		fmt.Fprintf(cfg.Stderr(), "Error during shutdown: %v\n", err)
+		exiter.Exit(1)
+		return nil
+	}
+
 	exiter.Exit(0)
 	return nil
 }
@@ -1,12 +1,13 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
 
 const (
-	exploreBaseURL = "https://pokeapi.co/api/v2/location-area/"
+	explorePath = "location-area/"
 )
 
 type LocationArea struct {
@@ -18,29 +19,37 @@ type LocationArea struct {
 	} `json:"pokemon_encounters"`
 }
 
-func CommandExploreMap(cfg *Config, args ...string) error {
+func CommandExploreMap(ctx context.Context, cfg *Config, args ...string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("explore command requires a location area name")
 	}
 
 	locationName := strings.ToLower(args[0])
-	url := fmt.Sprintf("%s%s", exploreBaseURL, locationName)
 
-	locationArea, err := GetResponse[LocationArea](url, cfg.Cache)
+	locationArea, locationName, err := lookupName[LocationArea](ctx, cfg, "location area", explorePath, locationName, cfg.allAreas)
 	if err != nil {
 		return fmt.Errorf("failed to explore %s: %w", locationName, err)
 	}
 
-	fmt.Printf("Exploring %s...\n", locationName)
-	fmt.Println("Found Pokemon:")
+	cfg.names().addAreas(locationName)
+	for _, encounter := range locationArea.PokemonEncounters {
+		cfg.names().addPokemon(encounter.Pokemon.Name)
+	}
+
+	if cfg.structured() {
+		return writeResult(cfg, ExploreResult{Area: locationName, LocationArea: locationArea})
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "Exploring %s...\n", locationName)
+	fmt.Fprintln(cfg.Stdout(), "Found Pokemon:")
 
 	if len(locationArea.PokemonEncounters) == 0 {
-		fmt.Println("No Pokemon found in this area.")
+		fmt.Fprintln(cfg.Stdout(), "No Pokemon found in this area.")
 		return nil
 	}
 
 	for _, encounter := range locationArea.PokemonEncounters {
-		fmt.Printf(" - %s\n", encounter.Pokemon.Name)
+		fmt.Fprintf(cfg.Stdout(), " - %s\n", encounter.Pokemon.Name)
 	}
 
 	return nil
@@ -1,19 +1,137 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // CommandHelp displays the help message with all available commands and their descriptions.
-// It prints a welcome message followed by usage information for each registered command.
-// Returns nil on success, or an error if printing fails.
-func CommandHelp(cfg *Config, args ...string) error {
-	fmt.Println("Welcome to the Pokedex!")
-	fmt.Println("Usage:")
-	fmt.Println()
+// It prints a welcome message followed by the commands grouped by category, then the
+// user's aliases and macros. With a command name it shows that command's detailed help.
+// Returns nil on success, or an error if there is no such command.
+//
+// Usage: help [command]
+// Example: help catch
+func CommandHelp(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) > 0 {
+		return commandDetails(cfg, args[0])
+	}
+
+	fmt.Fprintln(cfg.Stdout(), "Welcome to the Pokedex!")
+	fmt.Fprintln(cfg.Stdout(), "Usage: <command> [args...]")
+	fmt.Fprintln(cfg.Stdout())
+
+	byCategory := make(map[string][]CliCommand)
 	for _, cmd := range GetCommands() {
-		fmt.Printf("%s: %s\n", cmd.Name, cmd.Description)
+		byCategory[cmd.Category] = append(byCategory[cmd.Category], cmd)
+	}
+	for _, category := range categoryOrder {
+		commands := byCategory[category]
+		sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+
+		fmt.Fprintf(cfg.Stdout(), "%s:\n", category)
+		for _, cmd := range commands {
+			fmt.Fprintf(cfg.Stdout(), "  %s: %s\n", cmd.Name, cmd.Description)
+		}
+		fmt.Fprintln(cfg.Stdout())
+	}
+
+	if len(cfg.Aliases) > 0 {
+		printDefinitions(cfg, "aliases", cfg.Aliases)
+		fmt.Fprintln(cfg.Stdout())
+	}
+	if len(cfg.Macros) > 0 {
+		printDefinitions(cfg, "macros", cfg.Macros)
+		fmt.Fprintln(cfg.Stdout())
+	}
+
+	fmt.Fprintln(cfg.Stdout(), "Run 'help <command>' for its arguments and examples.")
+	return nil
+}
+
+// commandDetails prints the usage, arguments, examples and aliases of the command called
+// name. For an alias it explains what the alias stands for first; for a macro it shows its steps.
+// An unknown name returns an *UnknownNameError suggesting the closest commands.
+func commandDetails(cfg *Config, name string) error {
+	if body, exists := cfg.Macros[name]; exists {
+		fmt.Fprintf(cfg.Stdout(), "%s is a macro: %s\n", name, body)
+		return nil
+	}
+	if expansion, exists := cfg.Aliases[name]; exists {
+		fmt.Fprintf(cfg.Stdout(), "%s is an alias for %s\n", name, expansion)
+		if cmd, exists := GetCommands()[aliasCommand(expansion)]; exists {
+			fmt.Fprintln(cfg.Stdout())
+			printCommandDetails(cfg, cmd)
+		}
+		return nil
+	}
+
+	cmd, exists := GetCommands()[name]
+	if !exists {
+		corrected, err := cfg.correctName("command", name, commandNames(context.Background(), cfg), nil)
+		if err != nil {
+			return err
+		}
+		return commandDetails(cfg, corrected)
 	}
-	fmt.Println()
+
+	printCommandDetails(cfg, cmd)
 	return nil
 }
+
+// printCommandDetails prints the detailed help of cmd.
+func printCommandDetails(cfg *Config, cmd CliCommand) {
+	out := cfg.Stdout()
+	fmt.Fprintf(out, "%s: %s\n\n", cmd.Name, cmd.Description)
+	fmt.Fprintf(out, "Usage: %s\n", cmd.UsageLine())
+
+	if len(cmd.Args) > 0 {
+		width := 0
+		for _, arg := range cmd.Args {
+			width = max(width, len(arg.Name))
+		}
+
+		fmt.Fprintln(out, "\nArguments:")
+		for _, arg := range cmd.Args {
+			var notes []string
+			if arg.Optional {
+				notes = append(notes, "optional")
+			}
+			if len(arg.Values) > 0 {
+				notes = append(notes, "one of "+joinOr(arg.Values))
+			}
+			description := arg.Description
+			if len(notes) > 0 {
+				description += " (" + strings.Join(notes, ", ") + ")"
+			}
+			fmt.Fprintf(out, "  %-*s  %s\n", width, arg.Name, description)
+		}
+	}
+
+	if len(cmd.Examples) > 0 {
+		fmt.Fprintln(out, "\nExamples:")
+		for _, example := range cmd.Examples {
+			fmt.Fprintf(out, "  %s\n", example)
+		}
+	}
+
+	var aliases []string
+	for _, alias := range sortedKeys(cfg.Aliases) {
+		if aliasCommand(cfg.Aliases[alias]) == cmd.Name {
+			aliases = append(aliases, alias)
+		}
+	}
+	if len(aliases) > 0 {
+		fmt.Fprintf(out, "\nAliases: %s\n", strings.Join(aliases, ", "))
+	}
+}
+
+// aliasCommand returns the name of the command an alias runs, or "" if its expansion is empty.
+func aliasCommand(expansion string) string {
+	if words := ParseLine(expansion); len(words) > 0 {
+		return words[0]
+	}
+	return ""
+}
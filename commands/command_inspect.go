@@ -2,12 +2,14 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	imgcolor "image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
 	"math"
 	"os"
 	"regexp"
@@ -38,8 +40,14 @@ func getVisualLength(text string) int {
 	return len(ansiRegex.ReplaceAllString(text, ""))
 }
 
-// getTerminalWidth returns the terminal width, or 0 if unable to determine
-func getTerminalWidth() int {
+// getTerminalWidth returns the width of the terminal w writes to, or 0 if unable to determine
+// (including when w is not a file, such as a buffer capturing output)
+func getTerminalWidth(w io.Writer) int {
+	file, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+
 	type winsize struct {
 		Row    uint16
 		Col    uint16
@@ -49,7 +57,7 @@ func getTerminalWidth() int {
 
 	ws := &winsize{}
 	retCode, _, _ := syscall.Syscall(syscall.SYS_IOCTL,
-		uintptr(os.Stdout.Fd()),
+		uintptr(file.Fd()),
 		uintptr(syscall.TIOCGWINSZ),
 		uintptr(unsafe.Pointer(ws)))
 
@@ -72,7 +80,7 @@ func getTerminalWidth() int {
 //
 // Usage: inspect <pokemon_name>
 // Example: inspect pikachu
-func CommandInspect(cfg *Config, args ...string) error {
+func CommandInspect(ctx context.Context, cfg *Config, args ...string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("inspect command requires a Pokemon name")
 	}
@@ -81,27 +89,41 @@ func CommandInspect(cfg *Config, args ...string) error {
 
 	pokemon, exists := cfg.Pokedex[pokemonName]
 	if !exists {
-		fmt.Printf("you have not caught that pokemon\n")
-		return nil
+		corrected, err := cfg.correctName("caught Pokemon", pokemonName, sortedKeys(cfg.Pokedex), nil)
+		if err != nil {
+			return err
+		}
+		pokemon = cfg.Pokedex[corrected]
+	}
+	if cfg.structured() {
+		return writeResult(cfg, pokemon)
 	}
 
 	// Check terminal width for ASCII art display
 	// Only switch to text-only if we can detect width AND it's narrow
-	terminalWidth := getTerminalWidth()
+	terminalWidth := getTerminalWidth(cfg.Stdout())
 	if terminalWidth > 0 && terminalWidth < minTerminalWidth {
 		// Terminal too narrow - show text-only display
-		displayPokemonTextOnly(pokemon)
-		fmt.Printf("\n%s\n",
+		displayPokemonTextOnly(cfg.Stdout(), pokemon)
+		fmt.Fprintf(cfg.Stdout(), "\n%s\n",
 			color.New(color.FgYellow).Sprint("💡 Terminal too narrow for ASCII art. Resize to at least 130 characters wide to see Pokemon sprite!"))
 		return nil
 	}
 	// Default to ASCII art mode if width unknown (like during tests) or wide enough
 
+	// Download sprites from the configured mirror; the saved Pokedex keeps the original URLs
+	pokemon.SpriteURL = cfg.spriteURL(pokemon.SpriteURL)
+	pokemon.SpriteShiny = cfg.spriteURL(pokemon.SpriteShiny)
+	pokemon.SpriteOfficial = cfg.spriteURL(pokemon.SpriteOfficial)
+
 	// Try to get colorblock art from sprite
-	asciiArt := getColorblockArt(pokemon)
+	asciiArt := getColorblockArt(ctx, pokemon)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Create the full display with ASCII art
-	displayPokemon(pokemon, asciiArt)
+	displayPokemon(cfg.Stdout(), pokemon, asciiArt)
 
 	return nil
 }
@@ -109,7 +131,7 @@ func CommandInspect(cfg *Config, args ...string) error {
 // getASCIIArt downloads sprite and converts to ASCII art using a simple, direct approach.
 // No complex configuration - uses optimal settings for high-quality 80x40 ASCII art.
 // Falls back to a simple Pokemon ball if sprite unavailable.
-func getASCIIArt(pokemon Pokemon) []string {
+func getASCIIArt(ctx context.Context, pokemon Pokemon) []string {
 	// Try official artwork first for best quality, fallback to regular sprite
 	spriteURL := pokemon.SpriteOfficial
 	if spriteURL == "" {
@@ -121,7 +143,7 @@ func getASCIIArt(pokemon Pokemon) []string {
 	}
 
 	// Download with simple caching
-	imageData, err := sprites.DownloadAndCacheSprite(spriteURL)
+	imageData, err := sprites.DownloadAndCacheSprite(ctx, spriteURL)
 	if err != nil {
 		return getFallbackASCII()
 	}
@@ -167,7 +189,7 @@ func getFallbackASCII() []string {
 // getColorblockArt converts Pokemon sprites to high-quality colorblock art using Unicode half-blocks.
 // This provides 2x higher vertical resolution than traditional block rendering by using the ▄ character
 // with background color for top pixel and foreground color for bottom pixel.
-func getColorblockArt(pokemon Pokemon) []string {
+func getColorblockArt(ctx context.Context, pokemon Pokemon) []string {
 	// Try official artwork first for best quality, fallback to regular sprite
 	spriteURL := pokemon.SpriteOfficial
 	if spriteURL == "" {
@@ -179,7 +201,7 @@ func getColorblockArt(pokemon Pokemon) []string {
 	}
 
 	// Download with simple caching
-	imageData, err := sprites.DownloadAndCacheSprite(spriteURL)
+	imageData, err := sprites.DownloadAndCacheSprite(ctx, spriteURL)
 	if err != nil {
 		return getFallbackASCII()
 	}
@@ -464,32 +486,32 @@ func colorDistance(c1, c2 imgcolor.Color) float64 {
 // displayPokemon shows the Pokemon info with ASCII art in authentic Pokedex style.
 // Mimics the classic Pokedex layout with name/art at top and About/Types sections below.
 // Uses type-based colors (Fire=red, Water=blue, Electric=yellow, etc.) for visual appeal.
-func displayPokemon(pokemon Pokemon, asciiArt []string) {
+func displayPokemon(w io.Writer, pokemon Pokemon, asciiArt []string) {
 
 	// Display Pokemon name and number (centered above ASCII art)
 	nameContent := color.New(color.Bold, color.FgWhite, color.Underline).Sprintf("%s", strings.Title(pokemon.Name))
 	numberContent := color.New(color.FgWhite, color.Underline).Sprintf("#%d", pokemon.ID)
-	
+
 	// Center the name and number above ASCII art
 	nameLineLength := getVisualLength(nameContent + "  " + numberContent)
 	namePadding := (asciiWidth - nameLineLength) / 2
 	if namePadding < 0 {
 		namePadding = 0
 	}
-	
-	fmt.Printf("%s%s  %s\n", strings.Repeat(" ", namePadding), nameContent, numberContent)
-	fmt.Println() // Space before ASCII art
+
+	fmt.Fprintf(w, "%s%s  %s\n", strings.Repeat(" ", namePadding), nameContent, numberContent)
+	fmt.Fprintln(w) // Space before ASCII art
 
 	// Display ASCII art centered
 	for _, line := range asciiArt {
-		fmt.Println(line)
+		fmt.Fprintln(w, line)
 	}
 
-	fmt.Println() // Space after ASCII art
+	fmt.Fprintln(w) // Space after ASCII art
 
 	// Create the bottom info section in Pokedex style
 	// Left side: About section, Right side: Types section
-	
+
 	// Build About section
 	aboutLines := []string{
 		color.New(color.Bold, color.Underline).Sprint("About"),
@@ -497,7 +519,7 @@ func displayPokemon(pokemon Pokemon, asciiArt []string) {
 		fmt.Sprintf("%.1f kg", float64(pokemon.Weight)/10), // Convert hg to kg
 		"Weight",
 		"",
-		fmt.Sprintf("%.1f m", float64(pokemon.Height)/10), // Convert dm to m  
+		fmt.Sprintf("%.1f m", float64(pokemon.Height)/10), // Convert dm to m
 		"Height",
 		"",
 		fmt.Sprintf("%d", pokemon.BaseExperience),
@@ -509,12 +531,12 @@ func displayPokemon(pokemon Pokemon, asciiArt []string) {
 		color.New(color.Bold, color.Underline).Sprint("Types"),
 		"",
 	}
-	
+
 	// Add types with colors and background
 	for _, pokemonType := range pokemon.Types {
 		typeName := strings.Title(pokemonType)
 		lowerType := strings.ToLower(pokemonType)
-		
+
 		// Create type badge with background color and bold text for readability
 		var typeBadge string
 		switch lowerType {
@@ -557,19 +579,19 @@ func displayPokemon(pokemon Pokemon, asciiArt []string) {
 		default:
 			typeBadge = color.New(color.FgWhite, color.BgBlack, color.Bold).Sprint(" " + typeName + " ")
 		}
-		
+
 		typesLines = append(typesLines, typeBadge)
 	}
-	
+
 	typesLines = append(typesLines, "", color.New(color.Bold, color.Underline).Sprint("Stats"))
-	
+
 	// Add battle stats
 	for _, stat := range pokemon.Stats {
 		parts := strings.Split(stat, ": ")
 		if len(parts) == 2 {
 			statName := strings.Title(parts[0])
 			statValue := parts[1]
-			
+
 			// Create simple stat display
 			statNum := 0
 			fmt.Sscanf(statValue, "%d", &statNum)
@@ -577,7 +599,7 @@ func displayPokemon(pokemon Pokemon, asciiArt []string) {
 			if barLength > 10 {
 				barLength = 10
 			}
-			
+
 			bar := strings.Repeat("█", barLength) + strings.Repeat("░", 10-barLength)
 			typesLines = append(typesLines, fmt.Sprintf("%s: %s [%s]", statName, statValue, bar))
 		}
@@ -620,14 +642,14 @@ func displayPokemon(pokemon Pokemon, asciiArt []string) {
 	for i := 0; i < maxLines; i++ {
 		aboutLine := aboutLines[i]
 		typesLine := typesLines[i]
-		
+
 		// Pad about line to consistent width
 		if len(aboutLine) > aboutWidth {
 			aboutLine = aboutLine[:aboutWidth]
 		}
 		aboutPadding := aboutWidth - getVisualLength(aboutLine)
-		
-		fmt.Printf("%s%-*s%s%s\n", 
+
+		fmt.Fprintf(w, "%s%-*s%s%s\n",
 			strings.Repeat(" ", sectionPadding),
 			aboutWidth, aboutLine+strings.Repeat(" ", aboutPadding),
 			strings.Repeat(" ", sectionSpacing),
@@ -636,7 +658,7 @@ func displayPokemon(pokemon Pokemon, asciiArt []string) {
 }
 
 // displayPokemonTextOnly shows Pokemon info without ASCII art for narrow terminals
-func displayPokemonTextOnly(pokemon Pokemon) {
+func displayPokemonTextOnly(w io.Writer, pokemon Pokemon) {
 	// Colors for different types
 	typeColors := map[string]*color.Color{
 		"fire":     color.New(color.FgRed),
@@ -660,11 +682,11 @@ func displayPokemonTextOnly(pokemon Pokemon) {
 	}
 
 	// Simple text-only display for narrow terminals
-	fmt.Printf("\n%s\n", color.New(color.Bold).Sprintf("=== %s (#%d) ===", strings.Title(pokemon.Name), pokemon.ID))
+	fmt.Fprintf(w, "\n%s\n", color.New(color.Bold).Sprintf("=== %s (#%d) ===", strings.Title(pokemon.Name), pokemon.ID))
 
-	fmt.Printf("Height: %d dm\n", pokemon.Height)
-	fmt.Printf("Weight: %d hg\n", pokemon.Weight)
-	fmt.Printf("Base Experience: %d\n", pokemon.BaseExperience)
+	fmt.Fprintf(w, "Height: %d dm\n", pokemon.Height)
+	fmt.Fprintf(w, "Weight: %d hg\n", pokemon.Weight)
+	fmt.Fprintf(w, "Base Experience: %d\n", pokemon.BaseExperience)
 
 	// Display types with colors
 	if len(pokemon.Types) > 0 {
@@ -679,17 +701,17 @@ func displayPokemonTextOnly(pokemon Pokemon) {
 				typeStr += strings.Title(pokemonType)
 			}
 		}
-		fmt.Println(typeStr)
+		fmt.Fprintln(w, typeStr)
 	}
 
 	// Display abilities
 	if len(pokemon.Abilities) > 0 {
-		fmt.Printf("Abilities: %s\n", strings.Join(pokemon.Abilities, ", "))
+		fmt.Fprintf(w, "Abilities: %s\n", strings.Join(pokemon.Abilities, ", "))
 	}
 
 	// Display stats with simple bars
 	if len(pokemon.Stats) > 0 {
-		fmt.Printf("\n%s\n", color.New(color.Bold).Sprint("STATS:"))
+		fmt.Fprintf(w, "\n%s\n", color.New(color.Bold).Sprint("STATS:"))
 		for _, stat := range pokemon.Stats {
 			parts := strings.Split(stat, ": ")
 			if len(parts) == 2 {
@@ -705,10 +727,10 @@ func displayPokemonTextOnly(pokemon Pokemon) {
 				}
 
 				bar := strings.Repeat("█", barLength) + strings.Repeat("░", 10-barLength)
-				fmt.Printf("%s: %s [%s]\n", statName, statValue, bar)
+				fmt.Fprintf(w, "%s: %s [%s]\n", statName, statValue, bar)
 			}
 		}
 	}
 
-	fmt.Println() // Extra spacing
+	fmt.Fprintln(w) // Extra spacing
 }
@@ -1,9 +1,12 @@
 package commands
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 const (
-	mapBaseURL = "https://pokeapi.co/api/v2/location-area/"
+	mapPath = "location-area/"
 )
 
 type AreaMaps struct {
@@ -19,13 +22,13 @@ type AreaMaps struct {
 // CommandGetMaps fetches and displays the next page of location area maps from the PokeAPI.
 // It updates the config with new pagination URLs for future navigation.
 // Returns an error if the API request fails or response parsing fails.
-func CommandGetMaps(cfg *Config, args ...string) error {
-	url := mapBaseURL
+func CommandGetMaps(ctx context.Context, cfg *Config, args ...string) error {
+	url := cfg.apiURL(mapPath)
 	if cfg.NextURL != "" {
 		url = cfg.NextURL
 	}
 
-	areaMaps, err := GetResponse[AreaMaps](url, cfg.Cache)
+	areaMaps, err := GetResponse[AreaMaps](ctx, url, cfg.Cache)
 	if err != nil {
 		return fmt.Errorf("failed to get maps: %w", err)
 	}
@@ -37,21 +40,19 @@ func CommandGetMaps(cfg *Config, args ...string) error {
 		}
 	}
 
-	printMaps(areaMaps)
-
-	return nil
+	return printMaps(cfg, areaMaps)
 }
 
 // CommandGetMapsBack fetches and displays the previous page of location area maps from the PokeAPI.
 // It updates the config with new pagination URLs for future navigation.
 // Returns an error if the API request fails or response parsing fails.
-func CommandGetMapsBack(cfg *Config, args ...string) error {
-	url := mapBaseURL
+func CommandGetMapsBack(ctx context.Context, cfg *Config, args ...string) error {
+	url := cfg.apiURL(mapPath)
 	if cfg.PreviousURL != "" {
 		url = cfg.PreviousURL
 	}
 
-	areaMaps, err := GetResponse[AreaMaps](url, cfg.Cache)
+	areaMaps, err := GetResponse[AreaMaps](ctx, url, cfg.Cache)
 	if err != nil {
 		return fmt.Errorf("failed to get previous maps: %w", err)
 	}
@@ -63,15 +64,22 @@ func CommandGetMapsBack(cfg *Config, args ...string) error {
 		}
 	}
 
-	printMaps(areaMaps)
-
-	return nil
+	return printMaps(cfg, areaMaps)
 }
 
 // printMaps outputs the names of all location areas from the provided AreaMaps struct to stdout.
-// Each area name is printed on a separate line. This function does not return any values.
-func printMaps(areaMaps AreaMaps) {
+// Each area name is printed on a separate line; in JSON or YAML mode the whole page is printed instead.
+func printMaps(cfg *Config, areaMaps AreaMaps) error {
 	for _, result := range areaMaps.Results {
-		fmt.Printf("%s\n", result.Name)
+		cfg.names().addAreas(result.Name)
 	}
+
+	if cfg.structured() {
+		return writeResult(cfg, areaMaps)
+	}
+
+	for _, result := range areaMaps.Results {
+		fmt.Fprintf(cfg.Stdout(), "%s\n", result.Name)
+	}
+	return nil
 }
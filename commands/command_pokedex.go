@@ -1,18 +1,12 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
 
-func CommandPokedex(cfg *Config, args ...string) error {
-	if len(cfg.Pokedex) == 0 {
-		fmt.Println("Your Pokedex is empty.")
-		return nil
-	}
-
-	fmt.Println("Your Pokedex:")
-
+func CommandPokedex(ctx context.Context, cfg *Config, args ...string) error {
 	// Get pokemon names and sort them alphabetically
 	names := make([]string, 0, len(cfg.Pokedex))
 	for name := range cfg.Pokedex {
@@ -20,8 +14,23 @@ func CommandPokedex(cfg *Config, args ...string) error {
 	}
 	sort.Strings(names)
 
+	if cfg.structured() {
+		pokemon := make([]Pokemon, len(names))
+		for i, name := range names {
+			pokemon[i] = cfg.Pokedex[name]
+		}
+		return writeResult(cfg, pokemon)
+	}
+
+	if len(cfg.Pokedex) == 0 {
+		fmt.Fprintln(cfg.Stdout(), "Your Pokedex is empty.")
+		return nil
+	}
+
+	fmt.Fprintln(cfg.Stdout(), "Your Pokedex:")
+
 	for _, name := range names {
-		fmt.Printf("  - %s\n", name)
+		fmt.Fprintf(cfg.Stdout(), "  - %s\n", name)
 	}
 
 	return nil
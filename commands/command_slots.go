@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// slotSnapshot is the payload stored in a named save slot.
+// It captures the whole session: the Pokedex plus the current map position.
+type slotSnapshot struct {
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	NextURL     string             `json:"next_url"`
+	PreviousURL string             `json:"previous_url"`
+	Pokedex     map[string]Pokemon `json:"pokedex"`
+}
+
+// CommandSave snapshots the current session into a named save slot.
+// Saving over an existing slot keeps its original creation time.
+//
+// Usage: save <slot>
+// Example: save ash
+func CommandSave(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("save command requires a slot name")
+	}
+	if cfg.Slots == nil {
+		return fmt.Errorf("save slots are not available")
+	}
+
+	slotName := args[0]
+	store, err := cfg.Slots.Store(slotName)
+	if err != nil {
+		return fmt.Errorf("invalid slot name: %w", err)
+	}
+
+	now := time.Now()
+	snapshot := slotSnapshot{
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		NextURL:     cfg.NextURL,
+		PreviousURL: cfg.PreviousURL,
+		Pokedex:     cfg.Pokedex,
+	}
+
+	// Preserve the creation time when overwriting a slot
+	var existing slotSnapshot
+	if err := store.Load(&existing); err == nil && !existing.CreatedAt.IsZero() {
+		snapshot.CreatedAt = existing.CreatedAt
+	}
+
+	if err := store.Save(snapshot); err != nil {
+		return fmt.Errorf("failed to save slot %s: %w", slotName, err)
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "Saved %d Pokemon to slot %s\n", len(cfg.Pokedex), slotName)
+	return nil
+}
+
+// CommandLoad replaces the current session with the contents of a named save slot.
+// Automatic saving is turned off for the rest of the session, with a warning, so the
+// slot's Pokedex never overwrites the automatically saved one; 'save' keeps progress.
+//
+// Usage: load <slot>
+// Example: load ash
+func CommandLoad(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("load command requires a slot name")
+	}
+	if cfg.Slots == nil {
+		return fmt.Errorf("save slots are not available")
+	}
+
+	slotName := args[0]
+	store, err := cfg.Slots.Store(slotName)
+	if err != nil {
+		return fmt.Errorf("invalid slot name: %w", err)
+	}
+
+	var snapshot slotSnapshot
+	if err := store.Load(&snapshot); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no save slot named %s", slotName)
+		}
+		return fmt.Errorf("failed to load slot %s: %w", slotName, err)
+	}
+
+	if snapshot.Pokedex == nil {
+		snapshot.Pokedex = make(map[string]Pokemon)
+	}
+	cfg.Pokedex = snapshot.Pokedex
+	cfg.NextURL = snapshot.NextURL
+	cfg.PreviousURL = snapshot.PreviousURL
+
+	fmt.Fprintf(cfg.Stdout(), "Loaded slot %s (%d Pokemon)\n", slotName, len(cfg.Pokedex))
+	if cfg.Store != nil {
+		cfg.Store = nil
+		fmt.Fprintf(cfg.Stderr(), "Warning: your Pokedex is no longer saved automatically this session. Use 'save %s' to keep your progress.\n", slotName)
+	}
+	return nil
+}
+
+// CommandSlots lists all save slots with their Pokemon count and timestamps.
+// Unreadable slots are listed as such and left untouched.
+func CommandSlots(ctx context.Context, cfg *Config, args ...string) error {
+	if cfg.Slots == nil {
+		return fmt.Errorf("save slots are not available")
+	}
+
+	names, err := cfg.Slots.Names()
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		fmt.Fprintln(cfg.Stdout(), "No save slots yet. Use 'save <slot>' to create one.")
+		return nil
+	}
+
+	fmt.Fprintln(cfg.Stdout(), "Save slots:")
+	for _, name := range names {
+		store, err := cfg.Slots.Store(name)
+		if err != nil {
+			continue
+		}
+
+		var snapshot slotSnapshot
+		if err := store.Peek(&snapshot); err != nil {
+			fmt.Fprintf(cfg.Stdout(), "  - %s (unreadable: %v)\n", name, err)
+			continue
+		}
+
+		fmt.Fprintf(cfg.Stdout(), "  - %s: %d Pokemon, created %s, updated %s\n",
+			name,
+			len(snapshot.Pokedex),
+			snapshot.CreatedAt.Local().Format(time.DateTime),
+			snapshot.UpdatedAt.Local().Format(time.DateTime))
+	}
+
+	return nil
+}
+
+// CommandDeleteSlot permanently removes a named save slot.
+//
+// Usage: delete-slot <slot>
+// Example: delete-slot ash
+func CommandDeleteSlot(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("delete-slot command requires a slot name")
+	}
+	if cfg.Slots == nil {
+		return fmt.Errorf("save slots are not available")
+	}
+
+	slotName := args[0]
+	if err := cfg.Slots.Delete(slotName); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no save slot named %s", slotName)
+		}
+		return err
+	}
+
+	fmt.Fprintf(cfg.Stdout(), "Deleted slot %s\n", slotName)
+	return nil
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+const (
+	// areaIndexPath and pokemonIndexPath list every location area and Pokemon in a single page
+	areaIndexPath    = "location-area/?offset=0&limit=10000"
+	pokemonIndexPath = "pokemon/?offset=0&limit=10000"
+)
+
+// nameIndex collects the location area and Pokemon names the session knows about.
+// Names seen in command results are added as they come in; the full lists are only
+// fetched from the API the first time they are needed.
+type nameIndex struct {
+	areas   map[string]bool
+	pokemon map[string]bool
+
+	areasFetched   bool
+	pokemonFetched bool
+}
+
+// names returns the session's name index, creating it on first use.
+func (cfg *Config) names() *nameIndex {
+	if cfg.nameIndex == nil {
+		cfg.nameIndex = &nameIndex{
+			areas:   make(map[string]bool),
+			pokemon: make(map[string]bool),
+		}
+	}
+	return cfg.nameIndex
+}
+
+// addAreas records location area names, e.g. from a page of map results.
+func (n *nameIndex) addAreas(names ...string) {
+	for _, name := range names {
+		n.areas[name] = true
+	}
+}
+
+// addPokemon records Pokemon names, e.g. from the encounters in an explored area.
+func (n *nameIndex) addPokemon(names ...string) {
+	for _, name := range names {
+		n.pokemon[name] = true
+	}
+}
+
+// allAreas returns every known location area name. The location-area index is fetched
+// on first use; if that fails (offline, for example) only the names seen so far are returned.
+func (cfg *Config) allAreas(ctx context.Context) []string {
+	n := cfg.names()
+	if !n.areasFetched {
+		if index, err := GetResponse[AreaMaps](ctx, cfg.apiURL(areaIndexPath), cfg.Cache); err == nil {
+			for _, result := range index.Results {
+				n.addAreas(result.Name)
+			}
+			n.areasFetched = true
+		}
+	}
+	return sortedKeys(n.areas)
+}
+
+// allPokemon returns every known Pokemon name, fetching the Pokemon index on first use like allAreas.
+func (cfg *Config) allPokemon(ctx context.Context) []string {
+	n := cfg.names()
+	if !n.pokemonFetched {
+		if index, err := GetResponse[pokemonList](ctx, cfg.apiURL(pokemonIndexPath), cfg.Cache); err == nil {
+			for _, result := range index.Results {
+				n.addPokemon(result.Name)
+			}
+			n.pokemonFetched = true
+		}
+	}
+	return sortedKeys(n.pokemon)
+}
+
+// Complete returns the possible completions of the last word of line, for tab completion
+// at the prompt. A line ending in a space completes a new, empty word.
+//
+// The first word completes to command, alias and macro names. Arguments complete to the
+// Values or Complete of the command's ArgSpec in that position, also after an alias.
+func Complete(ctx context.Context, cfg *Config, line string) []string {
+	words := strings.Fields(line)
+	partial := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		partial = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	if len(words) == 0 {
+		return withPrefix(commandNames(ctx, cfg), partial)
+	}
+
+	name := strings.ToLower(words[0])
+	if expansion, exists := cfg.Aliases[name]; exists {
+		expanded := ParseLine(expansion)
+		if len(expanded) == 0 {
+			return nil // a broken alias has nothing to complete
+		}
+		words = append(expanded, words[1:]...)
+		name = words[0]
+	}
+	cmd, exists := GetCommands()[name]
+	if !exists {
+		return nil
+	}
+
+	position := len(words) - 1
+	if position >= len(cmd.Args) {
+		if len(cmd.Args) == 0 || !cmd.Args[len(cmd.Args)-1].Repeated {
+			return nil
+		}
+		position = len(cmd.Args) - 1
+	}
+
+	arg := cmd.Args[position]
+	candidates := arg.Values
+	if candidates == nil && arg.Complete != nil {
+		candidates = arg.Complete(ctx, cfg)
+	}
+	return withPrefix(candidates, partial)
+}
+
+// The completions used by the ArgSpecs in GetCommands.
+
+// commandNames returns the names of every command, alias and macro.
+func commandNames(ctx context.Context, cfg *Config) []string {
+	names := sortedKeys(GetCommands())
+	names = append(names, definitionNames(ctx, cfg)...)
+	sort.Strings(names)
+	return names
+}
+
+// definitionNames returns the names of the aliases and macros.
+func definitionNames(ctx context.Context, cfg *Config) []string {
+	names := append(sortedKeys(cfg.Aliases), sortedKeys(cfg.Macros)...)
+	sort.Strings(names)
+	return names
+}
+
+// areaNames returns every known location area name, see allAreas.
+func areaNames(ctx context.Context, cfg *Config) []string {
+	return cfg.allAreas(ctx)
+}
+
+// pokemonNames returns every known Pokemon name, see allPokemon.
+func pokemonNames(ctx context.Context, cfg *Config) []string {
+	return cfg.allPokemon(ctx)
+}
+
+// caughtPokemonNames returns the names of the Pokemon in the Pokedex.
+func caughtPokemonNames(ctx context.Context, cfg *Config) []string {
+	return sortedKeys(cfg.Pokedex)
+}
+
+// slotNames returns the names of the save slots, or nil if there are none or they can't be read.
+func slotNames(ctx context.Context, cfg *Config) []string {
+	if cfg.Slots == nil {
+		return nil
+	}
+	names, err := cfg.Slots.Names()
+	if err != nil {
+		return nil
+	}
+	return names
+}
+
+// withPrefix returns the candidates that start with prefix, ignoring case.
+func withPrefix(candidates []string, prefix string) []string {
+	prefix = strings.ToLower(prefix)
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// sortedKeys returns the keys of m in alphabetical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat selects how commands print their results.
+type OutputFormat string
+
+const (
+	// FormatText is the human-readable output, used when Config.Output is empty
+	FormatText OutputFormat = "text"
+	// FormatJSON prints each result as an indented JSON document
+	FormatJSON OutputFormat = "json"
+	// FormatYAML prints each result as a YAML document, with the same keys as JSON
+	FormatYAML OutputFormat = "yaml"
+)
+
+// ParseOutputFormat returns the output format called name ("text", "json" or "yaml").
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(name)); format {
+	case FormatText, FormatJSON, FormatYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (expected text, json or yaml)", name)
+	}
+}
+
+// structured reports whether commands should print machine-readable results instead of text.
+func (cfg *Config) structured() bool {
+	return cfg.Output == FormatJSON || cfg.Output == FormatYAML
+}
+
+// ExploreResult is the structured result of explore.
+type ExploreResult struct {
+	Area string `json:"area"`
+	LocationArea
+}
+
+// CatchResult is the structured result of catch. Pokemon is only set when it was caught.
+type CatchResult struct {
+	Name          string   `json:"name"`
+	Caught        bool     `json:"caught"`
+	AlreadyCaught bool     `json:"already_caught,omitempty"`
+	Pokemon       *Pokemon `json:"pokemon,omitempty"`
+}
+
+// writeResult prints v in the configured structured format.
+//
+// YAML output is produced from the JSON encoding, so both formats use the
+// same keys (the existing JSON tags) and keep struct field order.
+func writeResult(cfg *Config, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	if cfg.Output == FormatYAML {
+		if data, err = jsonToYAML(data); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		_, err = cfg.Stdout().Write(data)
+		return err
+	}
+
+	_, err = fmt.Fprintf(cfg.Stdout(), "%s\n", data)
+	return err
+}
+
+// jsonToYAML converts a JSON document to block-style YAML.
+// JSON is valid YAML, so it is parsed into a node tree and re-encoded without the flow styles and quoting it was parsed with.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// clearStyle resets node and its children to the default YAML style.
+// Strings that would otherwise read as another type, such as "123" or "true", are still quoted by the encoder.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// CommandFormat shows or changes the output format for the rest of the session.
+//
+// Usage: format [text|json|yaml]
+// Example: format json
+func CommandFormat(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		current := cfg.Output
+		if current == "" {
+			current = FormatText
+		}
+		fmt.Fprintf(cfg.Stdout(), "Output format: %s\n", current)
+		return nil
+	}
+
+	format, err := ParseOutputFormat(args[0])
+	if err != nil {
+		return err
+	}
+	cfg.Output = format
+	fmt.Fprintf(cfg.Stdout(), "Output format set to %s\n", format)
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kiefbc/pokedexcli/internal/httputil"
+)
+
+// refreshGracePeriod is how long Shutdown lets background cache refreshes run
+// before cancelling them.
+const refreshGracePeriod = 2 * time.Second
+
+// pokedexSave is the payload stored in the save file.
+// Pokemon are stored using the Pokemon struct's own JSON encoding.
+type pokedexSave struct {
+	Pokedex map[string]Pokemon `json:"pokedex"`
+}
+
+// LoadPokedex restores the caught Pokemon from cfg.Store into cfg.Pokedex.
+// A missing save file is not an error - it simply means nothing has been caught yet.
+// Returns nil if no store is configured.
+func LoadPokedex(cfg *Config) error {
+	if cfg.Store == nil {
+		return nil
+	}
+
+	var saved pokedexSave
+	if err := cfg.Store.Load(&saved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if saved.Pokedex == nil {
+		saved.Pokedex = make(map[string]Pokemon)
+	}
+	cfg.Pokedex = saved.Pokedex
+
+	return nil
+}
+
+// SavePokedex writes cfg.Pokedex to cfg.Store.
+// Returns nil if no store is configured.
+func SavePokedex(cfg *Config) error {
+	if cfg.Store == nil {
+		return nil
+	}
+
+	if err := cfg.Store.Save(pokedexSave{Pokedex: cfg.Pokedex}); err != nil {
+		return fmt.Errorf("failed to save Pokedex: %w", err)
+	}
+
+	return nil
+}
+
+// Shutdown flushes and closes every subsystem before the program exits.
+// Background cache refreshes get up to refreshGracePeriod to finish first, then the
+// Pokedex is saved and the cache is closed.
+// All steps run even if an earlier one fails; the errors are joined.
+func Shutdown(cfg *Config) error {
+	httputil.Wait(refreshGracePeriod)
+
+	var errs []error
+	if err := SavePokedex(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Cache != nil {
+		if err := cfg.Cache.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close cache: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
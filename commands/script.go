@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxSourceDepth limits how deeply scripts may source other scripts, so a script
+// that sources itself fails instead of recursing forever.
+const maxSourceDepth = 8
+
+// sourceDepthKey is the context key holding the number of scripts currently being sourced.
+type sourceDepthKey struct{}
+
+// rawArgCommands take file paths as arguments, or command lines that may contain them,
+// so their arguments keep their case. Alias and macro definitions are folded when they run.
+var rawArgCommands = map[string]bool{
+	"source": true,
+	"alias":  true,
+	"macro":  true,
+}
+
+// ScriptError is returned for a command in a script that failed, naming where it is.
+type ScriptError struct {
+	Script  string // the script's name, e.g. "hunt.pdx" or "<stdin>"
+	Line    int
+	Command string
+	Err     error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %s: %v", e.Script, e.Line, e.Command, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ParseLine splits a command line into words and normalises them with NormalizeArgs.
+// A word starting with # begins a comment that runs to the end of the line.
+func ParseLine(line string) []string {
+	words := strings.Fields(line)
+	for i, word := range words {
+		if strings.HasPrefix(word, "#") {
+			words = words[:i]
+			break
+		}
+	}
+	return NormalizeArgs(words)
+}
+
+// NormalizeArgs lowercases the command name in words, since command names are all lowercase.
+// The arguments are left alone until Run has resolved any alias, see normalizeArgs.
+func NormalizeArgs(words []string) []string {
+	normalized := slices.Clone(words)
+	if len(normalized) > 0 {
+		normalized[0] = strings.ToLower(normalized[0])
+	}
+	return normalized
+}
+
+// normalizeArgs lowercases the arguments of the built-in command name, since Pokemon
+// and area names are all lowercase. Arguments of commands that take file paths keep their case.
+func normalizeArgs(name string, args []string) []string {
+	if rawArgCommands[name] {
+		return args
+	}
+	normalized := make([]string, len(args))
+	for i, arg := range args {
+		normalized[i] = strings.ToLower(arg)
+	}
+	return normalized
+}
+
+// RunScript executes the command lines read from r, one per line. name identifies the
+// script in error messages, e.g. "hunt.pdx:3: catch: ...".
+//
+// Blank lines and comments are skipped. "set -e" makes the script stop at the first failing
+// command and return its error; "set +e" turns that off again. Without it, failures are
+// reported on cfg.Stderr() as they happen and the script carries on, returning an error that
+// counts them at the end. Cancelling ctx stops the script.
+func RunScript(ctx context.Context, cfg *Config, r io.Reader, name string) error {
+	stopOnError := false
+	failures := 0
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		words := ParseLine(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+
+		if words[0] == "set" {
+			if len(words) != 2 || (words[1] != "-e" && words[1] != "+e") {
+				return fmt.Errorf("%s:%d: unsupported option (expected 'set -e' or 'set +e')", name, lineNumber)
+			}
+			stopOnError = words[1] == "-e"
+			continue
+		}
+
+		err := Run(ctx, cfg, words[0], words[1:]...)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		err = &ScriptError{Script: name, Line: lineNumber, Command: words[0], Err: err}
+		if stopOnError {
+			return err
+		}
+		fmt.Fprintf(cfg.Stderr(), "Error: %v\n", err)
+		failures++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d command(s) in %s failed", failures, name)
+	}
+
+	return nil
+}
+
+// CommandSource runs the commands in a script file, as if they were typed at the prompt.
+// See RunScript for the script format.
+//
+// Usage: source <file>
+// Example: source hunt.pdx
+func CommandSource(ctx context.Context, cfg *Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("source command requires a file name")
+	}
+
+	depth, _ := ctx.Value(sourceDepthKey{}).(int)
+	if depth >= maxSourceDepth {
+		return fmt.Errorf("scripts nested too deeply (max %d)", maxSourceDepth)
+	}
+
+	path := args[0]
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open script: %w", err)
+	}
+	defer file.Close()
+
+	return RunScript(context.WithValue(ctx, sourceDepthKey{}, depth+1), cfg, file, path)
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kiefbc/pokedexcli/internal/bundle"
+	"github.com/kiefbc/pokedexcli/internal/fuzzy"
+	"github.com/kiefbc/pokedexcli/internal/httputil"
+)
+
+// maxSuggestions is the most "did you mean" suggestions shown for a misspelt name.
+const maxSuggestions = 3
+
+// UnknownNameError is returned when a Pokemon or location area does not exist.
+// It lists the closest known names and wraps the error from the failed lookup, if any.
+type UnknownNameError struct {
+	Kind        string   // what was looked up, e.g. "Pokemon" or "location area"
+	Name        string   // the name that was not found
+	Suggestions []string // the closest known names, best first
+	Err         error
+}
+
+func (e *UnknownNameError) Error() string {
+	message := fmt.Sprintf("no %s named %q", e.Kind, e.Name)
+	if len(e.Suggestions) > 0 {
+		message += " - did you mean " + joinOr(e.Suggestions) + "?"
+	}
+	return message
+}
+
+func (e *UnknownNameError) Unwrap() error {
+	return e.Err
+}
+
+// correctName is called after name was not found among kind. It looks for the closest
+// candidates. With cfg.AutoCorrect set and a single best match, the correction is
+// reported on stderr and returned so the caller can retry with it; otherwise the
+// returned *UnknownNameError suggests up to maxSuggestions matches.
+func (cfg *Config) correctName(kind, name string, candidates []string, lookupErr error) (string, error) {
+	matches := fuzzy.Closest(name, candidates, maxSuggestions)
+
+	if cfg.AutoCorrect && len(matches) > 0 && (len(matches) == 1 || matches[0].Score < matches[1].Score) {
+		fmt.Fprintf(cfg.Stderr(), "No %s named %q, using %s\n", kind, name, matches[0].Name)
+		return matches[0].Name, nil
+	}
+
+	suggestions := make([]string, len(matches))
+	for i, match := range matches {
+		suggestions[i] = match.Name
+	}
+	return "", &UnknownNameError{Kind: kind, Name: name, Suggestions: suggestions, Err: lookupErr}
+}
+
+// joinOr joins names as "a", "a or b" or "a, b or c".
+func joinOr(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// lookupName fetches the resource called name under path, e.g. "pokemon/". If there is
+// no such resource, the closest names from candidates are suggested, or with auto-correct
+// the best match is fetched instead. Offline, a name missing from the bundle counts as not found.
+// Returns the resource and the name it was found under.
+func lookupName[T any](ctx context.Context, cfg *Config, kind, path, name string, candidates func(context.Context) []string) (T, string, error) {
+	result, err := GetResponse[T](ctx, cfg.apiURL(path+name), cfg.Cache)
+	var notFoundErr *httputil.NotFoundError
+	if !errors.As(err, &notFoundErr) && !errors.Is(err, bundle.ErrNotInBundle) {
+		return result, name, err
+	}
+
+	corrected, err := cfg.correctName(kind, name, candidates(ctx), err)
+	if err != nil {
+		return result, name, err
+	}
+	result, err = GetResponse[T](ctx, cfg.apiURL(path+corrected), cfg.Cache)
+	return result, corrected, err
+}
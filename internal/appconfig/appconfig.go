@@ -0,0 +1,228 @@
+// Package appconfig loads user settings for the Pokedex CLI.
+// Settings come from ~/.pokedex_config.json and can be overridden by environment
+// variables; main applies command-line flags on top, so the precedence is
+// flag > environment > config file > built-in default.
+package appconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kiefbc/pokedexcli/internal/fsutil"
+)
+
+const configFileName = ".pokedex_config.json"
+
+// Environment variables that override the config file.
+const (
+	EnvBaseURL        = "POKEDEX_API_BASE_URL"
+	EnvSpritesBaseURL = "POKEDEX_SPRITES_BASE_URL"
+)
+
+// Settings holds the user-configurable options. Empty fields mean "use the default".
+type Settings struct {
+	BaseURL        string `json:"base_url,omitempty"`         // PokeAPI root, e.g. http://localhost:8000/api/v2/
+	SpritesBaseURL string `json:"sprites_base_url,omitempty"` // mirror of the PokeAPI sprites repository
+	AutoCorrect    bool   `json:"auto_correct,omitempty"`     // retry misspelt names with the closest match
+
+	Aliases map[string]string `json:"aliases,omitempty"` // alternative command names, e.g. "c" for "catch"
+	Macros  map[string]string `json:"macros,omitempty"`  // named command sequences, e.g. "explore $1; catch $2"
+}
+
+// DefaultPath returns the default config file location, ~/.pokedex_config.json.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, configFileName), nil
+}
+
+// LoadOption configures optional Load behaviour.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	commands map[string]bool // built-in command names; nil skips checking alias and macro targets
+	warn     func(err error) // called for each alias or macro that is dropped
+}
+
+// WithCommands makes Load drop aliases and macros that run anything other than one
+// of the named commands or another alias or macro.
+func WithCommands(names ...string) LoadOption {
+	return func(o *loadOptions) {
+		o.commands = make(map[string]bool, len(names))
+		for _, name := range names {
+			o.commands[name] = true
+		}
+	}
+}
+
+// WithWarnings makes Load report each alias or macro it drops to warn.
+func WithWarnings(warn func(err error)) LoadOption {
+	return func(o *loadOptions) {
+		o.warn = warn
+	}
+}
+
+// Load reads settings from the config file at path and then applies environment overrides.
+// A missing config file is not an error - the environment and defaults still apply.
+// Aliases and macros without a command are dropped, as are those running unknown commands
+// with WithCommands, so one stale definition never stops the rest of the file from loading.
+func Load(path string, opts ...LoadOption) (Settings, error) {
+	var settings Settings
+	var options loadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &settings); err != nil {
+				return Settings{}, fmt.Errorf("invalid config file %s: %w", path, err)
+			}
+			for _, err := range settings.dropInvalidDefinitions(options.commands) {
+				if options.warn != nil {
+					options.warn(fmt.Errorf("config file %s: %w", path, err))
+				}
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return Settings{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	if value, ok := os.LookupEnv(EnvBaseURL); ok {
+		settings.BaseURL = value
+	}
+	if value, ok := os.LookupEnv(EnvSpritesBaseURL); ok {
+		settings.SpritesBaseURL = value
+	}
+
+	return settings, nil
+}
+
+// Update applies change to the settings stored in the config file at path and writes
+// them back, creating the file if needed. Only the file is read, so environment
+// overrides and flags are never saved.
+func Update(path string, change func(*Settings)) error {
+	var settings Settings
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	change(&settings)
+
+	data, err = json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save config file: %w", err)
+	}
+	return nil
+}
+
+// dropInvalidDefinitions removes every alias and macro that fails checkDefinition and
+// returns why each was dropped. Removing one can invalidate others that run it, so
+// the check repeats until nothing more is removed.
+func (s *Settings) dropInvalidDefinitions(commands map[string]bool) []error {
+	var errs []error
+	for {
+		invalid := false
+		for _, name := range sortedKeys(s.Aliases) {
+			if err := s.checkDefinition("alias", name, []string{s.Aliases[name]}, commands); err != nil {
+				delete(s.Aliases, name)
+				errs = append(errs, err)
+				invalid = true
+			}
+		}
+		for _, name := range sortedKeys(s.Macros) {
+			if err := s.checkDefinition("macro", name, strings.Split(s.Macros[name], ";"), commands); err != nil {
+				delete(s.Macros, name)
+				errs = append(errs, err)
+				invalid = true
+			}
+		}
+		if !invalid {
+			return errs
+		}
+	}
+}
+
+// checkDefinition checks that every step of an alias or macro starts with a command. With
+// commands set, that command must be one of them or another alias or macro.
+func (s Settings) checkDefinition(kind, name string, steps []string, commands map[string]bool) error {
+	found := false
+	for _, step := range steps {
+		command := firstWord(step)
+		if command == "" {
+			continue
+		}
+		found = true
+		_, isAlias := s.Aliases[command]
+		_, isMacro := s.Macros[command]
+		if commands != nil && !commands[command] && !isAlias && !isMacro {
+			return fmt.Errorf("%s %q runs unknown command %q", kind, name, command)
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s %q has no command", kind, name)
+	}
+	return nil
+}
+
+// firstWord returns the lowercased command name a command line starts with, or ""
+// if it is blank or only a comment.
+func firstWord(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// sortedKeys returns the keys of m in alphabetical order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks that every configured URL is an absolute http or https URL.
+func (s Settings) Validate() error {
+	if err := validateURL("API base URL", s.BaseURL); err != nil {
+		return err
+	}
+	return validateURL("sprites base URL", s.SpritesBaseURL)
+}
+
+// validateURL accepts an empty value or an absolute http(s) URL with a host.
+func validateURL(name, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s %q (expected an http or https URL)", name, value)
+	}
+
+	return nil
+}
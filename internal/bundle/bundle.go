@@ -0,0 +1,179 @@
+// Package bundle stores PokeAPI responses and sprites in a single compressed archive
+// so the Pokedex can run without a network. A bundle is a zip file with one entry per
+// URL; entries are named after the URL without its scheme, e.g. pokeapi.co/api/v2/pokemon/pikachu.
+// Bundles are tied to the API base URL they were built from.
+package bundle
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const bundleFileName = ".pokedex_bundle.zip"
+
+// ErrNotInBundle is returned by Reader.Get for a URL the bundle does not contain.
+var ErrNotInBundle = errors.New("not in the offline bundle")
+
+// DefaultPath returns the default bundle location, ~/.pokedex_bundle.zip.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, bundleFileName), nil
+}
+
+// entryName returns the archive entry name for url.
+// The scheme is dropped so that http and https URLs share an entry, and a trailing
+// slash becomes "/index" since zip reserves names ending in a slash for directories.
+func entryName(url string) string {
+	if _, rest, found := strings.Cut(url, "://"); found {
+		url = rest
+	}
+	if strings.HasSuffix(url, "/") {
+		url += "index"
+	}
+	return url
+}
+
+// Writer builds a bundle. The archive is written to a temporary file next to the
+// destination and only renamed into place by Close, so an interrupted build never
+// replaces a working bundle.
+type Writer struct {
+	path string
+	file *os.File
+	zw   *zip.Writer
+	seen map[string]bool
+}
+
+// Create starts a new bundle that will be written to path.
+func Create(path string) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	return &Writer{
+		path: path,
+		file: file,
+		zw:   zip.NewWriter(file),
+		seen: make(map[string]bool),
+	}, nil
+}
+
+// Add stores data as the response for url. Adding the same URL twice keeps the first copy.
+func (w *Writer) Add(url string, data []byte) error {
+	name := entryName(url)
+	if w.seen[name] {
+		return nil
+	}
+
+	entry, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", url, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", url, err)
+	}
+
+	w.seen[name] = true
+	return nil
+}
+
+// Has reports whether url has already been added.
+func (w *Writer) Has(url string) bool {
+	return w.seen[entryName(url)]
+}
+
+// Len returns the number of entries added so far.
+func (w *Writer) Len() int {
+	return len(w.seen)
+}
+
+// Close finishes the archive and moves it to its destination.
+func (w *Writer) Close() error {
+	if err := w.zw.Close(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("failed to save bundle: %w", err)
+	}
+	return nil
+}
+
+// Abort discards the partially written bundle. The previous bundle, if any, is left untouched.
+func (w *Writer) Abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// Reader serves responses from a bundle. It is safe for concurrent use.
+type Reader struct {
+	zr    *zip.ReadCloser
+	index map[string]*zip.File
+}
+
+// Open opens the bundle at path for reading.
+// A missing bundle is reported with an error wrapping os.ErrNotExist.
+func Open(path string) (*Reader, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	index := make(map[string]*zip.File, len(zr.File))
+	for _, file := range zr.File {
+		index[file.Name] = file
+	}
+
+	return &Reader{zr: zr, index: index}, nil
+}
+
+// Get returns the stored response for url, or an error wrapping ErrNotInBundle.
+func (r *Reader) Get(url string) ([]byte, error) {
+	file, ok := r.index[entryName(url)]
+	if !ok {
+		return nil, fmt.Errorf("%s is %w", url, ErrNotInBundle)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from bundle: %w", url, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from bundle: %w", url, err)
+	}
+	return data, nil
+}
+
+// Len returns the number of responses in the bundle.
+func (r *Reader) Len() int {
+	return len(r.index)
+}
+
+// Close closes the underlying archive.
+func (r *Reader) Close() error {
+	return r.zr.Close()
+}
@@ -0,0 +1,263 @@
+// Package fakeapi provides an in-process fake PokeAPI for hermetic tests.
+// It serves a small, fixed slice of the real API from embedded fixtures: the paginated
+// location-area and Pokemon lists, a few location areas, a few Pokemon with their species and sprites.
+//
+// Point commands at it through Config.BaseURL and Config.SpritesBaseURL:
+//
+//	server := fakeapi.NewServer()
+//	defer server.Close()
+//	cfg := &commands.Config{BaseURL: server.BaseURL(), SpritesBaseURL: server.SpritesBaseURL()}
+package fakeapi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	apiPrefix     = "/api/v2/"
+	spritesPrefix = "/sprites/"
+
+	// baseURLPlaceholder is replaced by BaseURL in fixture JSON, so links between
+	// resources point back at the fake server the way a self-hosted mirror's would.
+	baseURLPlaceholder = "{{BASE_URL}}"
+
+	defaultPageSize = 20
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Server is a running fake PokeAPI. Close it when done.
+type Server struct {
+	*httptest.Server
+
+	areas       []string          // every location area name, in API order
+	pokemon     []string          // every Pokemon name, in ID order
+	pokemonByID map[string]string // Pokemon ID to name, for /pokemon/<id> lookups
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// NewServer starts a fake PokeAPI on a local port.
+// It panics if the embedded fixtures are malformed, which is a bug in this package.
+func NewServer() *Server {
+	pokemon, pokemonByID := mustIndexPokemon()
+	s := &Server{
+		areas:       mustLoadAreas(),
+		pokemon:     pokemon,
+		pokemonByID: pokemonByID,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the API root to use as Config.BaseURL, with a trailing slash.
+func (s *Server) BaseURL() string {
+	return s.URL + apiPrefix
+}
+
+// SpritesBaseURL returns the sprites mirror root to use as Config.SpritesBaseURL.
+func (s *Server) SpritesBaseURL() string {
+	return s.URL + spritesPrefix
+}
+
+// Requests returns the path and query of every request received so far, in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// serveHTTP routes a request to the location-area, pokemon or sprite handler.
+// Anything without a fixture gets a 404, matching PokeAPI's behaviour for unknown resources.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	s.mu.Unlock()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if spritePath, ok := strings.CutPrefix(r.URL.Path, spritesPrefix); ok {
+		s.serveSprite(w, spritePath)
+		return
+	}
+
+	resource, ok := strings.CutPrefix(r.URL.Path, apiPrefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	kind, name, _ := strings.Cut(strings.Trim(resource, "/"), "/")
+	switch {
+	case kind == "location-area" && name == "":
+		s.serveList(w, r, kind, s.areas)
+	case kind == "location-area":
+		s.serveFixture(w, kind, s.areaName(name))
+	case kind == "pokemon" && name == "":
+		s.serveList(w, r, kind, s.pokemon)
+	case kind == "pokemon" || kind == "pokemon-species":
+		s.serveFixture(w, kind, s.pokemonName(name))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveList serves one page of the list of kind resources, honouring offset and limit.
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request, kind string, names []string) {
+	offset := queryInt(r, "offset", 0)
+	limit := queryInt(r, "limit", defaultPageSize)
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	offset = min(max(offset, 0), len(names))
+	end := min(offset+limit, len(names))
+
+	type result struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	page := struct {
+		Count    int      `json:"count"`
+		Next     *string  `json:"next"`
+		Previous *string  `json:"previous"`
+		Results  []result `json:"results"`
+	}{
+		Count:   len(names),
+		Results: []result{},
+	}
+
+	for _, name := range names[offset:end] {
+		page.Results = append(page.Results, result{
+			Name: name,
+			URL:  fmt.Sprintf("%s%s/%s/", s.BaseURL(), kind, name),
+		})
+	}
+	if end < len(names) {
+		next := fmt.Sprintf("%s%s/?offset=%d&limit=%d", s.BaseURL(), kind, end, limit)
+		page.Next = &next
+	}
+	if offset > 0 {
+		previous := fmt.Sprintf("%s%s/?offset=%d&limit=%d", s.BaseURL(), kind, max(offset-limit, 0), limit)
+		page.Previous = &previous
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// serveFixture serves fixtures/<dir>/<name>.json with placeholders filled in.
+func (s *Server) serveFixture(w http.ResponseWriter, dir, name string) {
+	data, err := fixtures.ReadFile(path.Join("fixtures", dir, name+".json"))
+	if name == "" || err != nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(strings.ReplaceAll(string(data), baseURLPlaceholder, s.BaseURL())))
+}
+
+// serveSprite serves a sprite for any path ending in <id>.png. Every variant
+// (front, back, shiny, official artwork) of a Pokemon shares the same image.
+func (s *Server) serveSprite(w http.ResponseWriter, spritePath string) {
+	data, err := fixtures.ReadFile(path.Join("fixtures", "sprites", path.Base(spritePath)))
+	if err != nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(data)
+}
+
+// areaName resolves a location area given by name or numeric ID.
+func (s *Server) areaName(nameOrID string) string {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		if id < 1 || id > len(s.areas) {
+			return ""
+		}
+		return s.areas[id-1]
+	}
+	return nameOrID
+}
+
+// pokemonName resolves a Pokemon or species given by name or numeric ID.
+func (s *Server) pokemonName(nameOrID string) string {
+	if name, ok := s.pokemonByID[nameOrID]; ok {
+		return name
+	}
+	return nameOrID
+}
+
+// queryInt returns the integer query parameter key, or fallback if it is missing or invalid.
+func queryInt(r *http.Request, key string, fallback int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// mustLoadAreas reads the ordered list of location area names.
+func mustLoadAreas() []string {
+	data, err := fixtures.ReadFile("fixtures/location-areas.json")
+	if err != nil {
+		panic(fmt.Sprintf("fakeapi: %v", err))
+	}
+
+	var areas []string
+	if err := json.Unmarshal(data, &areas); err != nil {
+		panic(fmt.Sprintf("fakeapi: invalid location-areas.json: %v", err))
+	}
+	return areas
+}
+
+// mustIndexPokemon returns the name of every Pokemon fixture in ID order,
+// and a map from each Pokemon's ID to its name.
+func mustIndexPokemon() ([]string, map[string]string) {
+	entries, err := fixtures.ReadDir("fixtures/pokemon")
+	if err != nil {
+		panic(fmt.Sprintf("fakeapi: %v", err))
+	}
+
+	type pokemon struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	all := make([]pokemon, 0, len(entries))
+	for _, entry := range entries {
+		data, err := fixtures.ReadFile(path.Join("fixtures", "pokemon", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("fakeapi: %v", err))
+		}
+
+		var p pokemon
+		if err := json.Unmarshal(data, &p); err != nil {
+			panic(fmt.Sprintf("fakeapi: invalid %s: %v", entry.Name(), err))
+		}
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+
+	names := make([]string, len(all))
+	byID := make(map[string]string, len(all))
+	for i, p := range all {
+		names[i] = p.Name
+		byID[strconv.Itoa(p.ID)] = p.Name
+	}
+	return names, byID
+}
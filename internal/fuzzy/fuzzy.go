@@ -0,0 +1,79 @@
+// Package fuzzy finds the names closest to a misspelt one, for "did you mean" suggestions.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match is a candidate name and how far it is from the name that was asked for.
+// Lower scores are closer; an exact match scores 0.
+type Match struct {
+	Name  string
+	Score int
+}
+
+// Closest returns up to limit candidates close enough to name to be a likely typo of it,
+// best first. Candidates that extend name, like "eterna-city-area" for "eterna-city",
+// score 1 however much longer they are.
+//
+// A candidate is close enough when its score is at most a third of the length of name
+// (but at least 1), so longer names tolerate more mistakes.
+func Closest(name string, candidates []string, limit int) []Match {
+	name = strings.ToLower(name)
+	maxScore := max(1, len([]rune(name))/3)
+
+	var matches []Match
+	for _, candidate := range candidates {
+		score := Distance(name, strings.ToLower(candidate))
+		if score > 1 && name != "" && strings.HasPrefix(strings.ToLower(candidate), name) {
+			score = 1
+		}
+		if score <= maxScore {
+			matches = append(matches, Match{Name: candidate, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score < matches[j].Score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Distance returns the edit distance between a and b: the number of single-character
+// insertions, deletions, substitutions and swaps of adjacent characters needed to turn
+// one into the other (the optimal string alignment distance).
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	// Three rolling rows of the dynamic programming table: two back, previous and current
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(t)]
+}
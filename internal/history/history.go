@@ -0,0 +1,129 @@
+// Package history keeps the REPL's command history in ~/.pokedex_history, one command per line.
+//
+// Every session appends each command to the file as soon as it is entered rather than
+// writing its whole history on exit, so several sessions open at once share one history
+// in the order commands were typed. A session notices commands added by the others with
+// Changed and reloads them with Load.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kiefbc/pokedexcli/internal/fsutil"
+)
+
+const historyFileName = ".pokedex_history"
+
+// DefaultMaxEntries is the number of commands kept in the history file.
+const DefaultMaxEntries = 1000
+
+// DefaultPath returns the default history location, ~/.pokedex_history.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, historyFileName), nil
+}
+
+// File is a history file shared between sessions.
+type File struct {
+	path       string
+	maxEntries int
+
+	// size and modTime describe the file as this session last left it, so that
+	// Changed can tell when another session has written to it since
+	size    int64
+	modTime time.Time
+}
+
+// New returns the history file at path, keeping at most maxEntries commands.
+func New(path string, maxEntries int) *File {
+	return &File{path: path, maxEntries: maxEntries}
+}
+
+// Path returns the location of the history file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Load reads the history, oldest command first. A missing file is an empty history.
+// If the file has grown past the entry limit it is trimmed to the newest commands.
+func (f *File) Load() ([]string, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		f.size, f.modTime = 0, time.Time{}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			entries = append(entries, line)
+		}
+	}
+
+	if len(entries) > f.maxEntries {
+		entries = entries[len(entries)-f.maxEntries:]
+		trimmed := strings.Join(entries, "\n") + "\n"
+		if err := fsutil.WriteFileAtomic(f.path, []byte(trimmed), 0600); err != nil {
+			return nil, fmt.Errorf("failed to trim history: %w", err)
+		}
+	}
+
+	f.remember()
+	return entries, nil
+}
+
+// Add appends a command to the history file. Blank commands are ignored.
+func (f *File) Add(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	// Commands appended by other sessions must still be reported by Changed afterwards
+	changedElsewhere := f.Changed()
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+
+	if !changedElsewhere {
+		f.remember()
+	}
+	return nil
+}
+
+// Changed reports whether the file has been written by another session since this one last loaded or added to it.
+func (f *File) Changed() bool {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.size != 0
+	}
+	return info.Size() != f.size || !info.ModTime().Equal(f.modTime)
+}
+
+// remember records the current size and modification time of the file.
+func (f *File) remember() {
+	f.size, f.modTime = 0, time.Time{}
+	if info, err := os.Stat(f.path); err == nil {
+		f.size, f.modTime = info.Size(), info.ModTime()
+	}
+}
@@ -0,0 +1,101 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The errors below describe why a request failed, so callers can tell a missing
+// resource from an overloaded server or a slow network with errors.As.
+// Each carries the requested URL, and those for an unsuccessful response its HTTP status.
+
+// NotFoundError is returned for a 404 response: the requested resource does not exist.
+type NotFoundError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.URL, e.Status)
+}
+
+// RateLimitedError is returned for a 429 response: too many requests were made.
+type RateLimitedError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // zero when the server did not send Retry-After
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.URL, e.Status)
+}
+
+// ServerError is returned for a 5xx response: the server failed or is unavailable.
+type ServerError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // zero when the server did not send Retry-After
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.URL, e.Status)
+}
+
+// StatusError is returned for any other non-200 response, such as 400 or 403.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.URL, e.Status)
+}
+
+// TimeoutError is returned when no response arrived in time.
+type TimeoutError struct {
+	URL string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("request timed out: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when a 200 response is not the JSON that was expected.
+type DecodeError struct {
+	URL string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("invalid JSON from %s: %v", e.URL, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// newStatusError returns the error for a non-200 response to a request for url.
+func newStatusError(url string, resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return &NotFoundError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitedError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode >= 500:
+		return &ServerError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	default:
+		return &StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+}
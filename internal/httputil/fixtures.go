@@ -0,0 +1,242 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kiefbc/pokedexcli/internal/fsutil"
+)
+
+// manifestName is the file in a fixtures directory that maps each recorded URL to its response.
+const manifestName = "manifest.json"
+
+// ErrNotRecorded is returned in replay mode for a URL that has no recorded response.
+var ErrNotRecorded = errors.New("no recorded response")
+
+var (
+	transportMu      sync.RWMutex
+	defaultTransport http.RoundTripper // nil means http.DefaultTransport
+)
+
+// SetDefaultTransport makes every client created by NewClient use rt.
+// Pass nil to go back to http.DefaultTransport.
+func SetDefaultTransport(rt http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	defaultTransport = rt
+}
+
+// currentTransport returns the transport for new clients.
+func currentTransport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return defaultTransport
+}
+
+// fixture describes one recorded response. The body is stored in its own file so that
+// recorded JSON stays readable and sprites stay viewable as ordinary PNGs.
+type fixture struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	File        string `json:"file"` // body file, relative to the fixtures directory
+}
+
+// FixtureTransport is an http.RoundTripper that records responses to a fixtures directory
+// or replays them from it. Use it with SetDefaultTransport to capture a real session once
+// and play it back later without a network, for example as a regression test.
+type FixtureTransport struct {
+	dir    string
+	record bool
+	next   http.RoundTripper // only used when recording
+
+	mu       sync.Mutex
+	manifest map[string]fixture // nil until loaded
+}
+
+// NewRecordTransport returns a transport that performs requests with next
+// (http.DefaultTransport if nil) and saves every response to dir.
+func NewRecordTransport(dir string, next http.RoundTripper) *FixtureTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FixtureTransport{dir: dir, record: true, next: next}
+}
+
+// NewReplayTransport returns a transport that serves responses recorded in dir and never
+// touches the network. Requests for URLs that were not recorded fail with ErrNotRecorded.
+func NewReplayTransport(dir string) *FixtureTransport {
+	return &FixtureTransport{dir: dir}
+}
+
+// RoundTrip records or replays the response for req.
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("fixtures: only GET requests can be recorded, got %s", req.Method)
+	}
+
+	t.mu.Lock()
+	err := t.loadManifest()
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	key := req.URL.String()
+	if t.record {
+		return t.recordResponse(req, key)
+	}
+	return t.replayResponse(req, key)
+}
+
+// recordResponse performs req and saves the response before returning it.
+func (t *FixtureTransport) recordResponse(req *http.Request, key string) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: failed to read response body: %w", err)
+	}
+
+	entry := fixture{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		File:        fixtureFile(req.URL, resp.Header.Get("Content-Type")),
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(t.dir, filepath.FromSlash(entry.File)), body, 0644); err != nil {
+		return nil, fmt.Errorf("fixtures: failed to save %s: %w", key, err)
+	}
+
+	t.mu.Lock()
+	t.manifest[key] = entry
+	err = t.saveManifest()
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// replayResponse builds the response for req from the fixtures directory.
+func (t *FixtureTransport) replayResponse(req *http.Request, key string) (*http.Response, error) {
+	t.mu.Lock()
+	entry, ok := t.manifest[key]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fixtures: %w for %s in %s", ErrNotRecorded, key, t.dir)
+	}
+
+	body, err := os.ReadFile(filepath.Join(t.dir, filepath.FromSlash(entry.File)))
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: recorded response for %s is missing: %w", key, err)
+	}
+
+	header := make(http.Header)
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// loadManifest reads the manifest on first use. A missing manifest is only an error when replaying.
+// The caller must hold t.mu.
+func (t *FixtureTransport) loadManifest() error {
+	if t.manifest != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(t.dir, manifestName))
+	switch {
+	case errors.Is(err, os.ErrNotExist) && t.record:
+		t.manifest = make(map[string]fixture)
+		return nil
+	case err != nil:
+		return fmt.Errorf("fixtures: failed to read manifest: %w", err)
+	}
+
+	manifest := make(map[string]fixture)
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("fixtures: invalid manifest in %s: %w", t.dir, err)
+	}
+	t.manifest = manifest
+	return nil
+}
+
+// saveManifest writes the manifest with one entry per line, sorted by URL.
+// The caller must hold t.mu.
+func (t *FixtureTransport) saveManifest() error {
+	data, err := json.MarshalIndent(t.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fixtures: failed to encode manifest: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(t.dir, manifestName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("fixtures: failed to save manifest: %w", err)
+	}
+	return nil
+}
+
+// unsafeFileChars matches anything that shouldn't appear in a fixture file name.
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._=-]+`)
+
+// fixtureFile derives a readable body file name from a URL, e.g.
+// pokeapi.co/api/v2/location-area/index_offset=20_limit=20.json.
+func fixtureFile(u *url.URL, contentType string) string {
+	segments := []string{unsafeFileChars.ReplaceAllString(u.Host, "_")}
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment = unsafeFileChars.ReplaceAllString(segment, "_"); segment != "" && segment != "." && segment != ".." {
+			segments = append(segments, segment)
+		}
+	}
+
+	name := "index"
+	if !strings.HasSuffix(u.Path, "/") && len(segments) > 1 {
+		name = segments[len(segments)-1]
+		segments = segments[:len(segments)-1]
+	}
+	if u.RawQuery != "" {
+		name += "_" + unsafeFileChars.ReplaceAllString(u.RawQuery, "_")
+	}
+
+	ext := path.Ext(name)
+	name = strings.TrimSuffix(name, ext)
+	switch {
+	case strings.Contains(contentType, "json"):
+		ext = ".json"
+	case strings.HasPrefix(contentType, "image/png"):
+		ext = ".png"
+	case ext == "":
+		ext = ".body"
+	}
+
+	return path.Join(append(segments, name+ext)...)
+}
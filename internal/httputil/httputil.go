@@ -1,23 +1,80 @@
 package httputil
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kiefbc/pokedexcli/internal/pokecache"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	DefaultTimeoutSeconds = 10
 )
 
-// NewClient creates a new HTTP client with the specified timeout
+var (
+	// staleWhileRevalidate makes GetResponse serve expired cache entries immediately
+	// and refresh them in the background
+	staleWhileRevalidate atomic.Bool
+
+	// requests coalesces concurrent fetches of the same URL into a single HTTP call
+	requests singleflight.Group
+
+	// fetches holds the context of each shared fetch in requests, cancelled once no caller waits for it
+	fetchesMu sync.Mutex
+	fetches   = make(map[string]*sharedFetch)
+
+	// revalidation tracks background refreshes so Wait can drain them
+	revalidation sync.WaitGroup
+
+	// revalidationCtx is the context background refreshes run with, cancelled by Wait
+	// when they take too long
+	revalidationMu sync.Mutex
+
+	revalidationCtx, cancelRevalidation = context.WithCancel(context.Background())
+)
+
+// SetStaleWhileRevalidate enables or disables stale-while-revalidate mode.
+// When enabled, GetResponse returns an expired cache entry straight away if the cache
+// still holds one (see pokecache.WithStaleRetention) and refreshes it in the background.
+func SetStaleWhileRevalidate(enabled bool) {
+	staleWhileRevalidate.Store(enabled)
+}
+
+// Wait blocks until all background revalidations have finished, giving them up to
+// timeout before cancelling the rest, so a slow network cannot hold up the caller.
+// Refreshes started afterwards run normally.
+func Wait(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		revalidation.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+
+	revalidationMu.Lock()
+	cancelRevalidation()
+	revalidationCtx, cancelRevalidation = context.WithCancel(context.Background())
+	revalidationMu.Unlock()
+	<-done
+}
+
+// NewClient creates a new HTTP client with the specified timeout.
+// The client uses the transport set with SetDefaultTransport, if any.
 func NewClient(timeout time.Duration) *http.Client {
 	return &http.Client{
-		Timeout: timeout,
+		Timeout:   timeout,
+		Transport: currentTransport(),
 	}
 }
 
@@ -28,8 +85,17 @@ func NewDefaultClient() *http.Client {
 
 // GetResponse makes an HTTP GET request to the specified URL and parses the JSON response into the provided type T.
 // It first checks the cache for existing data. If not found, it makes the HTTP request and caches the response.
-// Returns the parsed response of type T and an error if the request fails, status is non-200, or JSON parsing fails.
-func GetResponse[T any](url string, cache *pokecache.Cache, client *http.Client) (T, error) {
+// In stale-while-revalidate mode an expired cache entry is returned instead and refreshed in the background.
+// Concurrent calls for the same URL share one HTTP request: every waiter decodes the same
+// response body, so they all receive equal results or the same error.
+// Transient failures are retried according to the current RetryPolicy.
+// With an offline source set (see SetOfflineSource) cache misses are served from it instead of HTTP.
+// Cancelling ctx makes this call return ctx.Err() straight away; a request shared with
+// other callers keeps running for them and still populates the cache. Once every caller
+// has given up, the request, its retries and any rate limiter wait are aborted.
+// Returns the parsed response of type T and an error if the request fails, status is non-200, or JSON parsing fails;
+// see errors.go for the types of error to check for with errors.As.
+func GetResponse[T any](ctx context.Context, url string, cache *pokecache.Cache, client *http.Client) (T, error) {
 	var result T
 
 	if cache == nil {
@@ -40,46 +106,127 @@ func GetResponse[T any](url string, cache *pokecache.Cache, client *http.Client)
 		client = NewDefaultClient()
 	}
 
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
 	// Check cache first
 	if cached, found := cache.Get(url); found {
 		err := json.Unmarshal(cached, &result)
 		if err != nil {
-			return result, fmt.Errorf("failed to unmarshal cached JSON: %w", err)
+			return result, &DecodeError{URL: url, Err: fmt.Errorf("cached response: %w", err)}
 		}
 		return result, nil
 	}
 
-	// Make HTTP request if not cached
-	resp, err := client.Get(url)
-	if err != nil {
-		return result, fmt.Errorf("failed to make request: %w", err)
+	// Serve stale data if allowed, falling through to a normal fetch if it doesn't decode
+	if staleWhileRevalidate.Load() {
+		if stale, _, found := cache.GetStale(url); found {
+			if err := json.Unmarshal(stale, &result); err == nil {
+				revalidate(url, cache, client)
+				return result, nil
+			}
+			result = *new(T)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf("received non-200 response: %s", resp.Status)
+	// Make HTTP request if not cached, joining any request already in flight for this URL.
+	// The shared request has its own context so one caller giving up doesn't fail the others.
+	fetchCtx, leave := joinFetch(url)
+	flight := requests.DoChan(url, func() (any, error) {
+		return fetchAndCache(fetchCtx, url, cache, client)
+	})
+
+	var res singleflight.Result
+	select {
+	case res = <-flight:
+		leave()
+	case <-ctx.Done():
+		leave()
+		return result, ctx.Err()
+	}
+	if res.Err != nil {
+		return result, res.Err
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	err := json.Unmarshal(res.Val.([]byte), &result)
 	if err != nil {
-		return result, fmt.Errorf("failed to read response body: %w", err)
+		return result, &DecodeError{URL: url, Err: err}
 	}
 
-	// Cache the response
-	err = cache.Add(url, body)
-	if err != nil {
-		return result, fmt.Errorf("failed to cache response: %w", err)
+	return result, nil
+}
+
+// GetResponseWithDefault is a convenience wrapper that uses the default client
+func GetResponseWithDefault[T any](ctx context.Context, url string, cache *pokecache.Cache) (T, error) {
+	return GetResponse[T](ctx, url, cache, nil)
+}
+
+// sharedFetch is the context of a fetch shared by every caller waiting for the same URL.
+type sharedFetch struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// joinFetch registers a caller waiting for url and returns the context the shared fetch
+// runs with, plus a function the caller must call once it stops waiting. When the last
+// waiter leaves the fetch is cancelled and forgotten, so the next caller starts afresh.
+func joinFetch(url string) (context.Context, func()) {
+	fetchesMu.Lock()
+	defer fetchesMu.Unlock()
+
+	f := fetches[url]
+	if f == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &sharedFetch{ctx: ctx, cancel: cancel}
+		fetches[url] = f
+	}
+	f.waiters++
+
+	return f.ctx, func() {
+		fetchesMu.Lock()
+		defer fetchesMu.Unlock()
+
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			delete(fetches, url)
+			requests.Forget(url)
+		}
 	}
+}
 
-	err = json.Unmarshal(body, &result)
+// fetchAndCache fetches url and stores the body in the cache.
+// Only valid JSON is cached, so a broken response can never replace good data.
+func fetchAndCache(ctx context.Context, url string, cache *pokecache.Cache, client *http.Client) ([]byte, error) {
+	body, err := Fetch(ctx, url, client)
 	if err != nil {
-		return result, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		return nil, err
 	}
 
-	return result, nil
+	if json.Valid(body) {
+		if err := cache.Add(url, body); err != nil {
+			return nil, fmt.Errorf("failed to cache response: %w", err)
+		}
+	}
+
+	return body, nil
 }
 
-// GetResponseWithDefault is a convenience wrapper that uses the default client
-func GetResponseWithDefault[T any](url string, cache *pokecache.Cache) (T, error) {
-	return GetResponse[T](url, cache, nil)
+// revalidate refreshes url in the background. Refreshes share the singleflight group
+// with foreground requests, so repeated stale reads trigger at most one fetch at a time.
+// Failures are ignored - the stale entry simply stays until the next attempt.
+func revalidate(url string, cache *pokecache.Cache, client *http.Client) {
+	revalidationMu.Lock()
+	ctx := revalidationCtx
+	revalidationMu.Unlock()
+
+	revalidation.Add(1)
+	go func() {
+		defer revalidation.Done()
+		requests.Do(url, func() (any, error) {
+			return fetchAndCache(ctx, url, cache, client)
+		})
+	}()
 }
@@ -0,0 +1,28 @@
+package httputil
+
+import "sync"
+
+// Source serves response bodies without touching the network, e.g. an offline bundle.
+type Source interface {
+	Get(url string) ([]byte, error)
+}
+
+var (
+	offlineMu     sync.RWMutex
+	offlineSource Source // nil means requests go over HTTP
+)
+
+// SetOfflineSource makes Fetch, and therefore GetResponse and sprite downloads, read every
+// response from src instead of making HTTP requests. Pass nil to go back online.
+func SetOfflineSource(src Source) {
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+	offlineSource = src
+}
+
+// currentOfflineSource returns the offline source, or nil when online.
+func currentOfflineSource() Source {
+	offlineMu.RLock()
+	defer offlineMu.RUnlock()
+	return offlineSource
+}
@@ -0,0 +1,43 @@
+package httputil
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/time/rate"
+)
+
+// PokeAPI's fair-use policy asks clients to be gentle, so by default all requests
+// from one process are limited to a steady rate with room for short bursts.
+const (
+	DefaultRequestsPerSecond = 10
+	DefaultBurst             = 20
+)
+
+// limiter is the token bucket shared by every outgoing request in the process,
+// including sprite downloads, so concurrent commands can't exceed the limit together.
+var limiter = rate.NewLimiter(DefaultRequestsPerSecond, DefaultBurst)
+
+// SetRateLimit configures the shared token bucket to allow requestsPerSecond
+// on average with bursts of up to burst requests. A non-positive rate disables limiting.
+func SetRateLimit(requestsPerSecond float64, burst int) {
+	if requestsPerSecond <= 0 {
+		limiter.SetLimit(rate.Inf)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	limiter.SetLimit(rate.Limit(requestsPerSecond))
+	limiter.SetBurst(burst)
+}
+
+// waitForToken blocks until the rate limiter allows another request.
+// It returns early with an error if ctx is cancelled, or if its deadline would
+// pass before a token becomes available.
+func waitForToken(ctx context.Context) error {
+	if err := limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("waiting for rate limiter: %w", err)
+	}
+	return nil
+}
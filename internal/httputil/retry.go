@@ -0,0 +1,217 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+// Only GET requests are made, so retrying is always safe; the policy decides
+// which failures are worth retrying and how long to wait between attempts.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled for each retry after that
+	MaxDelay    time.Duration // upper bound for any single delay, including Retry-After
+}
+
+// DefaultRetryPolicy retries twice with a jittered backoff starting at half a second.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+var (
+	retryMu     sync.RWMutex
+	retryPolicy = DefaultRetryPolicy
+)
+
+// SetRetryPolicy replaces the retry policy used for all requests.
+func SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryPolicy = policy
+}
+
+// currentRetryPolicy returns the retry policy in effect.
+func currentRetryPolicy() RetryPolicy {
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	return retryPolicy
+}
+
+// Fetch performs a GET request and returns the body of a 200 response, retrying
+// transient failures according to the current RetryPolicy. Failed responses and timeouts
+// are reported as the typed errors in errors.go, such as *NotFoundError and *TimeoutError. Every attempt first waits for
+// the shared rate limiter. Cancelling ctx aborts the wait, the request or the backoff.
+// The error of the last attempt is returned, annotated with the number of attempts made.
+// When an offline source is set, the body is read from it instead and no request is made.
+func Fetch(ctx context.Context, url string, client *http.Client) ([]byte, error) {
+	if src := currentOfflineSource(); src != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return src.Get(url)
+	}
+
+	if client == nil {
+		client = NewDefaultClient()
+	}
+
+	policy := currentRetryPolicy()
+	for attempt := 1; ; attempt++ {
+		body, err := fetchOnce(ctx, url, client)
+		if err == nil {
+			return body, nil
+		}
+
+		if attempt >= policy.MaxAttempts || !isRetryable(err) {
+			return nil, fmt.Errorf("%w (after %d %s)", err, attempt, pluralize(attempt, "attempt"))
+		}
+
+		timer := time.NewTimer(policy.delay(attempt, err))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (after %d %s)", ctx.Err(), attempt, pluralize(attempt, "attempt"))
+		}
+	}
+}
+
+// fetchOnce waits for the rate limiter, then performs a single GET request and
+// returns the body of a 200 response.
+func fetchOnce(ctx context.Context, url string, client *http.Client) ([]byte, error) {
+	if err := waitForToken(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, &TimeoutError{URL: url, Err: err}
+		}
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(url, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, &TimeoutError{URL: url, Err: err}
+		}
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return body, nil
+}
+
+// isRetryable reports whether err is a transient failure worth another attempt:
+// rate limiting (429), server errors (5xx), timeouts and dropped or refused connections.
+// Client errors such as 404 and permanent DNS failures are not retried.
+func isRetryable(err error) bool {
+	var rateLimitedErr *RateLimitedError
+	var serverErr *ServerError
+	if errors.As(err, &rateLimitedErr) || errors.As(err, &serverErr) {
+		return true
+	}
+	var notFoundErr *NotFoundError
+	var statusErr *StatusError
+	if errors.As(err, &notFoundErr) || errors.As(err, &statusErr) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// isTimeout reports whether err from an HTTP client means the request or the
+// response body took too long, as opposed to being cancelled or refused.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// delay returns how long to wait before the attempt after the given one.
+// A Retry-After header takes precedence; otherwise the delay doubles with each attempt
+// and is jittered between 50% and 100% so that clients don't retry in lockstep.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var rateLimitedErr *RateLimitedError
+	var serverErr *ServerError
+	switch {
+	case errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > 0:
+		return min(rateLimitedErr.RetryAfter, p.MaxDelay)
+	case errors.As(err, &serverErr) && serverErr.RetryAfter > 0:
+		return min(serverErr.RetryAfter, p.MaxDelay)
+	}
+
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+// Returns zero if the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// pluralize returns word with an "s" appended unless n is 1.
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
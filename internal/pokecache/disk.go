@@ -0,0 +1,156 @@
+package pokecache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kiefbc/pokedexcli/internal/fsutil"
+)
+
+const diskCacheDir = ".pokedex_cache"
+
+// diskTier is the optional persistent second tier of the cache.
+// Each entry lives in its own file named after the SHA-256 of its key, and files are
+// replaced atomically so several REPL processes can share one directory safely.
+type diskTier struct {
+	dir string
+}
+
+// diskEntry is the on-disk representation of a cache entry.
+// The key is stored alongside the value to guard against hash collisions.
+type diskEntry struct {
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+	Val       []byte    `json:"val"`
+}
+
+// DefaultDiskDir returns the default directory for the disk tier, ~/.pokedex_cache/.
+func DefaultDiskDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, diskCacheDir), nil
+}
+
+// path returns the file that stores key.
+func (d *diskTier) path(key string) string {
+	return filepath.Join(d.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(key))))
+}
+
+// get reads the entry for key from disk.
+// Unreadable or mismatched files are treated as misses.
+func (d *diskTier) get(key string) (*cacheEntry, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+
+	return &cacheEntry{key: key, createdAt: entry.CreatedAt, val: entry.Val}, true
+}
+
+// add writes entry to disk.
+func (d *diskTier) add(entry *cacheEntry) error {
+	data, err := json.Marshal(diskEntry{Key: entry.key, CreatedAt: entry.createdAt, Val: entry.val})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	return fsutil.WriteFileAtomic(d.path(entry.key), data, 0644)
+}
+
+// remove deletes the entry for key from disk, ignoring missing files.
+func (d *diskTier) remove(key string) {
+	os.Remove(d.path(key))
+}
+
+// reap deletes entry files last written more than ttl ago.
+// The file modification time matches the entry's creation time closely enough
+// for cleanup purposes and avoids decoding every file.
+func (d *diskTier) reap(ttl time.Duration) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+
+	for _, dirEntry := range entries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > ttl {
+			os.Remove(filepath.Join(d.dir, dirEntry.Name()))
+		}
+	}
+}
+
+// usage returns the number of entry files and their total size.
+func (d *diskTier) usage() (int, int64) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return 0, 0
+	}
+
+	count := 0
+	var size int64
+	for _, dirEntry := range entries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		count++
+		size += info.Size()
+	}
+
+	return count, size
+}
+
+// purge deletes every entry whose key starts with prefix and returns the deleted keys.
+// Files that cannot be decoded are only deleted when purging everything.
+func (d *diskTier) purge(prefix string) []string {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil
+	}
+
+	var removed []string
+	for _, dirEntry := range entries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(d.dir, dirEntry.Name())
+
+		var entry diskEntry
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+		if err != nil {
+			if prefix == "" {
+				os.Remove(path)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(entry.Key, prefix) && os.Remove(path) == nil {
+			removed = append(removed, entry.Key)
+		}
+	}
+
+	return removed
+}
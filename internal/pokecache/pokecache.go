@@ -1,68 +1,276 @@
 package pokecache
 
 import (
+	"container/list"
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type Cache struct {
-	mu    sync.RWMutex
-	cache map[string]cacheEntry
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List // most recently used entries at the front
 	ttl   time.Duration
+	disk  *diskTier // nil when the cache is memory-only
+
+	// How long expired entries are kept around for GetStale
+	staleRetention time.Duration
+
+	// Lifecycle of the background reaper
+	ticker    *time.Ticker
+	ctx       context.Context
+	stop      chan struct{} // closed by Close
+	done      chan struct{} // closed once the reaper has exited
+	closeOnce sync.Once
+
+	// Source of the current time, time.Now unless replaced by WithClock
+	now func() time.Time
+
+	// Memory budget - zero means unlimited
+	maxEntries int
+	maxBytes   int
+
+	// Instrumentation counters
+	bytes       int
+	hits        uint64
+	staleHits   uint64
+	misses      uint64
+	expirations uint64
+	evictions   uint64
 }
 
 type cacheEntry struct {
+	key       string
 	createdAt time.Time
 	val       []byte
 }
 
-func NewCache(ttl time.Duration) *Cache {
+// Stats is a snapshot of the cache's footprint and activity counters.
+type Stats struct {
+	Entries     int
+	Bytes       int
+	Hits        uint64
+	StaleHits   uint64
+	Misses      uint64
+	Expirations uint64
+	Evictions   uint64
+	TTL         time.Duration
+
+	// How often the background reaper removes expired entries
+	ReapInterval time.Duration
+
+	// Disk tier usage, zero when the cache is memory-only
+	DiskEntries int
+	DiskBytes   int64
+}
+
+// EntryInfo describes a single in-memory cache entry.
+type EntryInfo struct {
+	Key  string
+	Size int
+	Age  time.Duration
+}
+
+// Option configures optional Cache behaviour.
+type Option func(*Cache)
+
+// WithDiskDir enables a persistent second tier stored in dir.
+// Entries written to the disk tier survive restarts and can be shared between processes.
+// They keep their original creation time, so the TTL applies across restarts too.
+func WithDiskDir(dir string) Option {
+	return func(c *Cache) {
+		c.disk = &diskTier{dir: dir}
+	}
+}
+
+// WithMaxEntries limits the number of entries held in memory.
+// When the limit is reached the least recently used entry is evicted.
+func WithMaxEntries(n int) Option {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
+
+// WithMaxBytes limits the total size of the values held in memory.
+// Least recently used entries are evicted until the new value fits, and values
+// larger than the whole budget are not kept in memory at all.
+func WithMaxBytes(n int) Option {
+	return func(c *Cache) {
+		c.maxBytes = n
+	}
+}
+
+// WithStaleRetention keeps expired entries for an extra d after their TTL so that
+// GetStale can still return them, e.g. to serve stale data while revalidating.
+// Get never returns expired entries regardless of this setting.
+func WithStaleRetention(d time.Duration) Option {
+	return func(c *Cache) {
+		c.staleRetention = d
+	}
+}
+
+// WithContext ties the cache's background reaper to ctx.
+// Cancelling ctx has the same effect as calling Close.
+func WithContext(ctx context.Context) Option {
+	return func(c *Cache) {
+		c.ctx = ctx
+	}
+}
+
+// WithClock makes the cache read the current time from now instead of time.Now,
+// so entry ages can be controlled in tests. The reaper still ticks in real time.
+func WithClock(now func() time.Time) Option {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+func NewCache(ttl time.Duration, opts ...Option) *Cache {
 	newCache := &Cache{
-		cache: make(map[string]cacheEntry),
+		cache: make(map[string]*list.Element),
+		lru:   list.New(),
 		ttl:   ttl,
+		ctx:   context.Background(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		now:   time.Now,
 	}
 
-	// Run cleanup every 1/4 of TTL, minimum 1 minute
-	cleanupInterval := ttl / 4
-	if cleanupInterval < time.Minute {
-		cleanupInterval = time.Minute
+	for _, opt := range opts {
+		opt(newCache)
 	}
 
-	go newCache.reapLoop(cleanupInterval)
+	newCache.ticker = time.NewTicker(reapInterval(ttl))
+	go newCache.reapLoop()
 
 	return newCache
 }
 
-func (cacheData *Cache) reapLoop(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// reapInterval returns how often expired entries are removed for the given TTL:
+// every 1/4 of TTL, minimum 1 minute.
+func reapInterval(ttl time.Duration) time.Duration {
+	return max(ttl/4, time.Minute)
+}
+
+func (cacheData *Cache) reapLoop() {
+	defer close(cacheData.done)
+	defer cacheData.ticker.Stop()
+
+	for {
+		select {
+		case <-cacheData.ticker.C:
+		case <-cacheData.stop:
+			return
+		case <-cacheData.ctx.Done():
+			return
+		}
 
-	for range ticker.C {
 		cacheData.mu.Lock()
-		for key, entry := range cacheData.cache {
-			if time.Since(entry.createdAt) > cacheData.ttl {
-				delete(cacheData.cache, key)
+		maxAge := cacheData.ttl + cacheData.staleRetention
+		for _, elem := range cacheData.cache {
+			if cacheData.age(elem.Value.(*cacheEntry)) > maxAge {
+				cacheData.removeElement(elem)
+				cacheData.expirations++
 			}
 		}
 		cacheData.mu.Unlock()
+
+		if cacheData.disk != nil {
+			cacheData.disk.reap(maxAge)
+		}
 	}
 }
 
+// Get returns the value for key if it is still fresh.
+// Entries older than the TTL are never served, even if the reaper has not removed them yet.
 func (cacheData *Cache) Get(key string) ([]byte, bool) {
+	val, _, found := cacheData.GetWithAge(key)
+	return val, found
+}
+
+// GetWithAge is like Get but also returns how long ago the entry was added.
+func (cacheData *Cache) GetWithAge(key string) ([]byte, time.Duration, bool) {
+	return cacheData.get(key, false)
+}
+
+// GetStale returns the value for key even if it has expired, as long as it is still
+// inside the stale retention window (see WithStaleRetention). Compare the returned
+// age against TTL() to tell fresh and stale entries apart.
+func (cacheData *Cache) GetStale(key string) ([]byte, time.Duration, bool) {
+	return cacheData.get(key, true)
+}
+
+// get looks key up in memory and then on disk, promoting disk entries into memory.
+// Entries past the stale retention window are removed as they are found.
+func (cacheData *Cache) get(key string, allowStale bool) ([]byte, time.Duration, bool) {
 	if key == "" {
-		return nil, false
+		return nil, 0, false
 	}
 
-	cacheData.mu.RLock()
-	entry, exists := cacheData.cache[key]
-	cacheData.mu.RUnlock()
+	cacheData.mu.Lock()
+	if elem, exists := cacheData.cache[key]; exists {
+		defer cacheData.mu.Unlock()
+
+		entry := elem.Value.(*cacheEntry)
+		age := cacheData.age(entry)
+		if age > cacheData.ttl+cacheData.staleRetention {
+			cacheData.removeElement(elem)
+			cacheData.expirations++
+		} else {
+			cacheData.lru.MoveToFront(elem)
+		}
+		return cacheData.serve(entry, age, allowStale)
+	}
+	cacheData.mu.Unlock()
+
+	// Fall back to the disk tier
+	var entry *cacheEntry
+	exists := false
+	if cacheData.disk != nil {
+		entry, exists = cacheData.disk.get(key)
+	}
+
+	cacheData.mu.Lock()
+	defer cacheData.mu.Unlock()
 
 	if !exists {
-		return nil, false
+		if !allowStale {
+			cacheData.misses++
+		}
+		return nil, 0, false
 	}
 
-	return entry.val, true
+	age := cacheData.age(entry)
+	if age > cacheData.ttl+cacheData.staleRetention {
+		cacheData.disk.remove(key)
+		cacheData.expirations++
+	} else {
+		cacheData.store(entry)
+	}
+	return cacheData.serve(entry, age, allowStale)
+}
+
+// serve decides whether an entry of the given age may be returned and updates the counters.
+// Stale reads are counted separately so that a Get miss followed by a GetStale
+// fallback is not counted twice. Must be called with cacheData.mu held.
+func (cacheData *Cache) serve(entry *cacheEntry, age time.Duration, allowStale bool) ([]byte, time.Duration, bool) {
+	switch {
+	case age <= cacheData.ttl:
+		if !allowStale {
+			cacheData.hits++
+		}
+		return entry.val, age, true
+	case allowStale && age <= cacheData.ttl+cacheData.staleRetention:
+		cacheData.staleHits++
+		return entry.val, age, true
+	case !allowStale:
+		cacheData.misses++
+	}
+	return nil, 0, false
 }
 
 func (cacheData *Cache) Add(key string, val []byte) error {
@@ -70,9 +278,167 @@ func (cacheData *Cache) Add(key string, val []byte) error {
 		return fmt.Errorf("key and value must not be empty")
 	}
 
+	entry := &cacheEntry{key: key, createdAt: cacheData.now(), val: val}
+
 	cacheData.mu.Lock()
-	cacheData.cache[key] = cacheEntry{createdAt: time.Now(), val: val}
+	cacheData.store(entry)
 	cacheData.mu.Unlock()
 
+	// Persisting is best-effort - the in-memory entry is still usable if the write fails
+	if cacheData.disk != nil {
+		cacheData.disk.add(entry)
+	}
+
+	return nil
+}
+
+// Close stops the background reaper. Entries remain readable and writable afterwards,
+// they are just no longer removed in the background. Close is safe to call more than once.
+func (cacheData *Cache) Close() error {
+	cacheData.closeOnce.Do(func() {
+		close(cacheData.stop)
+	})
 	return nil
 }
+
+// Done returns a channel that is closed once the background reaper has exited,
+// after Close is called or the cache's context is cancelled.
+func (cacheData *Cache) Done() <-chan struct{} {
+	return cacheData.done
+}
+
+// Stats returns the current footprint, activity counters and disk tier usage.
+func (cacheData *Cache) Stats() Stats {
+	cacheData.mu.Lock()
+	stats := Stats{
+		Entries:     len(cacheData.cache),
+		Bytes:       cacheData.bytes,
+		Hits:        cacheData.hits,
+		StaleHits:   cacheData.staleHits,
+		Misses:      cacheData.misses,
+		Expirations: cacheData.expirations,
+		Evictions:   cacheData.evictions,
+		TTL:         cacheData.ttl,
+
+		ReapInterval: reapInterval(cacheData.ttl),
+	}
+	cacheData.mu.Unlock()
+
+	if cacheData.disk != nil {
+		stats.DiskEntries, stats.DiskBytes = cacheData.disk.usage()
+	}
+
+	return stats
+}
+
+// Entries returns information about every in-memory entry, sorted by key.
+func (cacheData *Cache) Entries() []EntryInfo {
+	cacheData.mu.Lock()
+	defer cacheData.mu.Unlock()
+
+	entries := make([]EntryInfo, 0, len(cacheData.cache))
+	for key, elem := range cacheData.cache {
+		entry := elem.Value.(*cacheEntry)
+		entries = append(entries, EntryInfo{
+			Key:  key,
+			Size: len(entry.val),
+			Age:  cacheData.age(entry),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries
+}
+
+// Purge removes every entry whose key starts with prefix from memory and disk.
+// An empty prefix clears the whole cache. Returns the number of distinct keys removed.
+func (cacheData *Cache) Purge(prefix string) int {
+	removed := make(map[string]bool)
+
+	cacheData.mu.Lock()
+	for key, elem := range cacheData.cache {
+		if strings.HasPrefix(key, prefix) {
+			cacheData.removeElement(elem)
+			removed[key] = true
+		}
+	}
+	cacheData.mu.Unlock()
+
+	if cacheData.disk != nil {
+		for _, key := range cacheData.disk.purge(prefix) {
+			removed[key] = true
+		}
+	}
+
+	return len(removed)
+}
+
+// TTL returns how long entries stay fresh.
+func (cacheData *Cache) TTL() time.Duration {
+	cacheData.mu.Lock()
+	defer cacheData.mu.Unlock()
+	return cacheData.ttl
+}
+
+// SetTTL changes how long entries stay fresh. It applies to existing entries too,
+// since freshness is always measured from each entry's creation time, and the
+// background reaper is rescheduled to match the new TTL.
+func (cacheData *Cache) SetTTL(ttl time.Duration) {
+	cacheData.mu.Lock()
+	defer cacheData.mu.Unlock()
+	cacheData.ttl = ttl
+	cacheData.ticker.Reset(reapInterval(ttl))
+}
+
+// DiskDir returns the directory of the disk tier, or "" when the cache is memory-only.
+func (cacheData *Cache) DiskDir() string {
+	if cacheData.disk == nil {
+		return ""
+	}
+	return cacheData.disk.dir
+}
+
+// store inserts or replaces entry as the most recently used, then evicts
+// least recently used entries until the cache is back within its budget.
+// Must be called with cacheData.mu held.
+func (cacheData *Cache) store(entry *cacheEntry) {
+	if elem, exists := cacheData.cache[entry.key]; exists {
+		cacheData.removeElement(elem)
+	}
+
+	if cacheData.maxBytes > 0 && len(entry.val) > cacheData.maxBytes {
+		return
+	}
+
+	cacheData.cache[entry.key] = cacheData.lru.PushFront(entry)
+	cacheData.bytes += len(entry.val)
+
+	for cacheData.overBudget() {
+		cacheData.removeElement(cacheData.lru.Back())
+		cacheData.evictions++
+	}
+}
+
+// overBudget reports whether the cache exceeds its entry or byte limit.
+// Must be called with cacheData.mu held.
+func (cacheData *Cache) overBudget() bool {
+	if cacheData.maxEntries > 0 && len(cacheData.cache) > cacheData.maxEntries {
+		return true
+	}
+	return cacheData.maxBytes > 0 && cacheData.bytes > cacheData.maxBytes
+}
+
+// age returns how long ago entry was created.
+func (cacheData *Cache) age(entry *cacheEntry) time.Duration {
+	return cacheData.now().Sub(entry.createdAt)
+}
+
+// removeElement drops elem from both the map and the recency list.
+// Must be called with cacheData.mu held.
+func (cacheData *Cache) removeElement(elem *list.Element) {
+	entry := cacheData.lru.Remove(elem).(*cacheEntry)
+	delete(cacheData.cache, entry.key)
+	cacheData.bytes -= len(entry.val)
+}
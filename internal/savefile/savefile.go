@@ -0,0 +1,187 @@
+// Package savefile persists application state to disk in a versioned, checksummed format.
+// Writes are atomic, so a crash mid-save never leaves a half-written file behind,
+// and files that fail validation are backed up instead of being silently discarded.
+package savefile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kiefbc/pokedexcli/internal/fsutil"
+)
+
+const (
+	// CurrentVersion is the schema version written by this build.
+	CurrentVersion = 1
+
+	saveFileName = ".pokedex_save.json"
+)
+
+// ErrUnsupportedVersion is returned when a save file was written by a newer schema version.
+var ErrUnsupportedVersion = errors.New("unsupported save file version")
+
+// CorruptError reports a save file that could not be decoded or failed its checksum.
+// The original file has been moved to BackupPath so that it can be inspected later.
+type CorruptError struct {
+	Path       string
+	BackupPath string
+	Err        error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("save file %s is corrupt (backed up to %s): %v", e.Path, e.BackupPath, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// envelope is the on-disk wrapper around the saved payload.
+// The checksum covers the compacted JSON of Data so that reformatting the file is harmless.
+type envelope struct {
+	Version  int             `json:"version"`
+	SavedAt  time.Time       `json:"saved_at"`
+	Checksum string          `json:"checksum"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Store reads and writes a single save file.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultPath returns the default save file location, ~/.pokedex_save.json.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, saveFileName), nil
+}
+
+// Path returns the location of the save file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Save encodes v and atomically replaces the save file with it.
+func (s *Store) Save(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode save data: %w", err)
+	}
+
+	env := envelope{
+		Version:  CurrentVersion,
+		SavedAt:  time.Now().UTC(),
+		Checksum: checksum(data),
+		Data:     data,
+	}
+
+	encoded, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode save file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := fsutil.WriteFileAtomic(s.path, encoded, 0644); err != nil {
+		return fmt.Errorf("failed to write save file: %w", err)
+	}
+	return nil
+}
+
+// Load decodes the save file into v.
+//
+// If the file does not exist the returned error wraps os.ErrNotExist.
+// If the file is truncated, malformed or fails its checksum it is renamed to a
+// timestamped backup and a *CorruptError is returned. Files written by a newer
+// schema version are left untouched and ErrUnsupportedVersion is returned.
+func (s *Store) Load(v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	corrupt, err := s.read(v)
+	if corrupt != nil {
+		return s.backupCorrupt(corrupt)
+	}
+	return err
+}
+
+// Peek decodes the save file into v like Load, but leaves a corrupt file where it is,
+// so files can be examined without side effects, e.g. to list them.
+func (s *Store) Peek(v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	corrupt, err := s.read(v)
+	if corrupt != nil {
+		return fmt.Errorf("save file %s is corrupt: %w", s.path, corrupt)
+	}
+	return err
+}
+
+// read decodes the save file into v. A file that exists but is invalid is reported
+// as corrupt, with the reason; any other failure is returned as err.
+// Must be called with s.mu held.
+func (s *Store) read(v any) (corrupt, err error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read save file: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err), nil
+	}
+
+	if env.Version > CurrentVersion {
+		return nil, fmt.Errorf("%w: file version %d, supported up to %d", ErrUnsupportedVersion, env.Version, CurrentVersion)
+	}
+	if env.Version < 1 || len(env.Data) == 0 {
+		return fmt.Errorf("missing version or data"), nil
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, env.Data); err != nil {
+		return fmt.Errorf("invalid data: %w", err), nil
+	}
+	if checksum(compacted.Bytes()) != env.Checksum {
+		return fmt.Errorf("checksum mismatch"), nil
+	}
+
+	if err := json.Unmarshal(env.Data, v); err != nil {
+		return fmt.Errorf("failed to decode data: %w", err), nil
+	}
+
+	return nil, nil
+}
+
+// backupCorrupt moves the current save file aside and wraps cause in a CorruptError.
+// Must be called with s.mu held.
+func (s *Store) backupCorrupt(cause error) error {
+	backupPath := fmt.Sprintf("%s.corrupt-%s", s.path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(s.path, backupPath); err != nil {
+		return fmt.Errorf("save file %s is corrupt and could not be backed up: %v: %w", s.path, cause, err)
+	}
+	return &CorruptError{Path: s.path, BackupPath: backupPath, Err: cause}
+}
+
+// checksum returns the hex-encoded SHA-256 digest of data.
+func checksum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
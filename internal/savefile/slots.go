@@ -0,0 +1,114 @@
+package savefile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	slotsDirName  = ".pokedex_slots"
+	slotExtension = ".json"
+	maxSlotName   = 50
+)
+
+var slotNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// Slots manages a directory of independently named save files.
+// Each slot is an ordinary Store, so slots get the same atomic writes and corruption handling.
+type Slots struct {
+	dir string
+}
+
+// NewSlots creates a slot manager that keeps its save files in dir.
+func NewSlots(dir string) *Slots {
+	return &Slots{dir: dir}
+}
+
+// DefaultSlotsDir returns the default slot directory, ~/.pokedex_slots/.
+func DefaultSlotsDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, slotsDirName), nil
+}
+
+// ValidateSlotName checks that name is safe to use as a file name.
+// Only letters, numbers, hyphens and underscores are allowed, which rules out path traversal.
+func ValidateSlotName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("slot name cannot be empty")
+	}
+	if len(name) > maxSlotName {
+		return fmt.Errorf("slot name too long (max %d characters)", maxSlotName)
+	}
+	if !slotNamePattern.MatchString(name) {
+		return fmt.Errorf("slot name contains invalid characters (only letters, numbers, hyphens, and underscores allowed)")
+	}
+	return nil
+}
+
+// Store returns the store for the named slot. The slot file need not exist yet.
+func (s *Slots) Store(name string) (*Store, error) {
+	if err := ValidateSlotName(name); err != nil {
+		return nil, err
+	}
+	return NewStore(s.path(name)), nil
+}
+
+// Exists reports whether the named slot has been saved.
+func (s *Slots) Exists(name string) bool {
+	if ValidateSlotName(name) != nil {
+		return false
+	}
+	_, err := os.Stat(s.path(name))
+	return err == nil
+}
+
+// Names returns the names of all saved slots in alphabetical order.
+// A missing slot directory simply means no slots have been saved.
+func (s *Slots) Names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read slot directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), slotExtension) {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), slotExtension)
+		if ValidateSlotName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Delete removes the named slot.
+// The returned error wraps os.ErrNotExist if the slot does not exist.
+func (s *Slots) Delete(name string) error {
+	if err := ValidateSlotName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(name)); err != nil {
+		return fmt.Errorf("failed to delete slot %s: %w", name, err)
+	}
+	return nil
+}
+
+// path returns the file backing the named slot. The name must already be validated.
+func (s *Slots) path(name string) string {
+	return filepath.Join(s.dir, name+slotExtension)
+}
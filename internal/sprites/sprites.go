@@ -4,17 +4,30 @@
 package sprites
 
 import (
+	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kiefbc/pokedexcli/internal/fsutil"
+	"github.com/kiefbc/pokedexcli/internal/httputil"
 )
 
 const spriteCacheDir = ".pokedex_sprites"
 
+// cacheDisabled makes every sprite download go to the network
+var cacheDisabled atomic.Bool
+
+// SetCacheEnabled turns the on-disk sprite cache on or off. With the cache off, sprites
+// are always downloaded and never written to disk, which is what fixture recording needs.
+func SetCacheEnabled(enabled bool) {
+	cacheDisabled.Store(!enabled)
+}
+
 // DownloadAndCacheSprite downloads Pokemon sprites and caches them locally for instant re-display.
 //
 // This function implements a simple but effective caching strategy:
@@ -23,20 +36,24 @@ const spriteCacheDir = ".pokedex_sprites"
 // - Returns cached version if available, otherwise downloads fresh copy
 // - Gracefully handles errors by continuing without caching if home dir unavailable
 //
+// Cancelling ctx aborts the download.
 // Returns the sprite data as []byte or an error if download fails.
-func DownloadAndCacheSprite(url string) ([]byte, error) {
+func DownloadAndCacheSprite(ctx context.Context, url string) ([]byte, error) {
 	if url == "" {
 		return nil, fmt.Errorf("empty sprite URL")
 	}
 
+	if cacheDisabled.Load() {
+		return downloadSprite(ctx, url)
+	}
+
 	// Create cache directory in user's home
-	homeDir, err := os.UserHomeDir()
+	cacheDir, err := CacheDir()
 	if err != nil {
 		// If we can't get home dir, just download without caching
-		return downloadSprite(url)
+		return downloadSprite(ctx, url)
 	}
 
-	cacheDir := filepath.Join(homeDir, spriteCacheDir)
 	os.MkdirAll(cacheDir, 0755) // Create if doesn't exist, ignore errors
 
 	// Generate simple cache filename from URL hash
@@ -49,36 +66,94 @@ func DownloadAndCacheSprite(url string) ([]byte, error) {
 	}
 
 	// Download the sprite
-	data, err := downloadSprite(url)
+	data, err := downloadSprite(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	// Try to cache it (don't fail if caching fails)
-	os.WriteFile(cacheFile, data, 0644)
+	// Cache it for next time (don't fail if caching fails). The write is atomic,
+	// so an interrupted write never leaves a truncated sprite behind.
+	fsutil.WriteFileAtomic(cacheFile, data, 0644)
 
 	return data, nil
 }
 
-// downloadSprite performs a simple HTTP download with a reasonable 10-second timeout.
-// This prevents the application from hanging on slow network connections while
-// still allowing enough time for sprite downloads to complete.
-func downloadSprite(url string) ([]byte, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+// CacheDir returns the sprite cache directory, ~/.pokedex_sprites/.
+func CacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, spriteCacheDir), nil
+}
 
-	resp, err := client.Get(url)
+// Usage returns the number of cached sprites and their total size in bytes.
+// A missing cache directory simply means nothing has been cached yet.
+func Usage() (int, int64, error) {
+	files, err := cachedFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to download sprite: %w", err)
+		return 0, 0, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("bad response: %s", resp.Status)
+	var size int64
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			size += info.Size()
+		}
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	return len(files), size, nil
+}
+
+// Purge deletes every cached sprite and returns how many were removed.
+func Purge() (int, error) {
+	files, err := cachedFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read sprite: %w", err)
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		if os.Remove(file) == nil {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// cachedFiles lists the paths of all cached sprite files.
+func cachedFiles() ([]string, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sprite cache: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".png") {
+			files = append(files, filepath.Join(cacheDir, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// downloadSprite downloads a sprite through the shared HTTP utility, so sprite downloads
+// get the same 10-second timeout and retry policy as API requests. This prevents the
+// application from hanging on slow network connections while still riding out brief outages.
+func downloadSprite(ctx context.Context, url string) ([]byte, error) {
+	data, err := httputil.Fetch(ctx, url, httputil.NewDefaultClient())
+	if err != nil {
+		return nil, fmt.Errorf("failed to download sprite: %w", err)
 	}
 
 	return data, nil
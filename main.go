@@ -1,64 +1,375 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/kiefbc/pokedexcli/commands"
+	"github.com/kiefbc/pokedexcli/internal/appconfig"
+	"github.com/kiefbc/pokedexcli/internal/bundle"
+	"github.com/kiefbc/pokedexcli/internal/httputil"
 	"github.com/kiefbc/pokedexcli/internal/pokecache"
+	"github.com/kiefbc/pokedexcli/internal/savefile"
+	"github.com/kiefbc/pokedexcli/internal/sprites"
+	"io"
 	"os"
-	"strings"
+	"os/signal"
+	"sync"
 	"time"
 )
 
 const (
 	maxCommandLength   = 50
 	cacheTimeoutLength = 5 * time.Minute
+	cacheMaxBytes      = 64 << 20 // 64 MiB of API responses held in memory
+	staleRetention     = 30 * time.Minute
 )
 
-// main starts the Pokedex CLI application and enters the REPL loop.
-// It continuously prompts for user input, processes commands, and executes them.
-// This function does not return - it runs until the program exits via a command.
+// main starts the Pokedex CLI application.
+// With a command on the command line, e.g. "pokedexcli catch pikachu", it runs just that command and
+// exits with its status. Otherwise it enters the REPL loop, which continuously prompts for user input,
+// processes commands, and executes them until the program exits via a command.
 func main() {
-	scanner := bufio.NewScanner(os.Stdin)
-	cache := pokecache.NewCache(cacheTimeoutLength)
+	settings, opts := loadSettings()
+
+	// Fixture recording and replay must see every request, so the disk caches are bypassed
+	fixtures := setupFixtures(opts)
+	if fixtures {
+		sprites.SetCacheEnabled(false)
+	}
+
+	cache := newCache(!fixtures)
+	httputil.SetStaleWhileRevalidate(true)
 
 	cfg := &commands.Config{
-		Cache:   cache,
-		Pokedex: make(map[string]commands.Pokemon),
+		BaseURL:        settings.BaseURL,
+		SpritesBaseURL: settings.SpritesBaseURL,
+		Cache:          cache,
+		Pokedex:        make(map[string]commands.Pokemon),
+		BundlePath:     opts.bundlePath,
+		Output:         opts.output,
+		AutoCorrect:    settings.AutoCorrect,
+		Aliases:        settings.Aliases,
+		Macros:         settings.Macros,
+		ConfigPath:     opts.configPath,
+		Out:            os.Stdout,
+		Err:            os.Stderr,
+	}
+
+	if opts.offline {
+		goOffline(cfg)
+	}
+	loadSavedPokedex(cfg)
+
+	args := flag.Args()
+	interrupts := &interruptHandler{}
+	interrupts.listen()
+
+	switch {
+	case len(args) > 0:
+		os.Exit(runOnce(interrupts, cfg, args))
+	case !isTerminal(os.Stdin):
+		// Output piped elsewhere, as in "pokedexcli | tee log", still gets the interactive prompt
+		os.Exit(runBatch(interrupts, cfg, os.Stdin))
+	}
+
+	runREPL(interrupts, cfg)
+}
+
+// isTerminal reports whether file is an interactive terminal rather than a pipe or regular file.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Exit codes for one-shot mode.
+const (
+	exitOK        = 0
+	exitError     = 1   // the command failed
+	exitUsage     = 2   // unknown command, invalid arguments or invalid flags
+	exitCancelled = 130 // interrupted with Ctrl-C, as shells report it
+)
+
+// runOnce runs a single command given on the command line and returns the process exit code.
+// Only the command's own output goes to stdout; errors go to stderr. The session is shut down
+// afterwards, so a caught Pokemon is saved just as it would be by the exit command.
+// "pokedexcli run <file>" is shorthand for "pokedexcli source <file>".
+func runOnce(interrupts *interruptHandler, cfg *commands.Config, args []string) int {
+	input := commands.NormalizeArgs(args)
+	command := input[0]
+	if command == "run" {
+		command = "source"
+	}
+
+	err := interrupts.run(cfg, command, input[1:]...)
+	return finish(cfg, command, err)
+}
+
+// runBatch runs the commands piped into stdin as a script, without prompts,
+// and returns the process exit code.
+func runBatch(interrupts *interruptHandler, cfg *commands.Config, input io.Reader) int {
+	err := interrupts.do(func(ctx context.Context) error {
+		return commands.RunScript(ctx, cfg, input, "<stdin>")
+	})
+	return finish(cfg, "", err)
+}
+
+// finish reports the error of a non-interactive run on stderr, shuts the session down
+// and returns the process exit code. command is empty for a script read from stdin.
+func finish(cfg *commands.Config, command string, err error) int {
+	code := exitOK
+	var scriptErr *commands.ScriptError
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(cfg.Stderr(), "Command cancelled")
+		code = exitCancelled
+	case command == "" || errors.As(err, &scriptErr):
+		// Script errors already name the file, line and command, and a usage
+		// error inside a script is a failure of the script rather than of its caller
+		fmt.Fprintf(cfg.Stderr(), "Error: %v\n", err)
+		code = exitError
+	case errors.Is(err, commands.ErrUnknownCommand):
+		fmt.Fprintf(cfg.Stderr(), "Unknown command %q (run 'pokedexcli help' for a list)\n", command)
+		code = exitUsage
+	case errors.Is(err, commands.ErrInvalidArgs):
+		fmt.Fprintf(cfg.Stderr(), "Error: %v\n", err)
+		code = exitUsage
+	case command == "source":
+		fmt.Fprintf(cfg.Stderr(), "Error: %v\n", err)
+		code = exitError
+	default:
+		fmt.Fprintf(cfg.Stderr(), "Error executing command '%s': %v\n", command, err)
+		code = exitError
+	}
+
+	if err := commands.Shutdown(cfg); err != nil {
+		fmt.Fprintf(cfg.Stderr(), "Error during shutdown: %v\n", err)
+		if code == exitOK {
+			code = exitError
+		}
+	}
+
+	return code
+}
+
+// options holds the command-line flags that are not part of the persistent settings,
+// and where those settings were read from.
+type options struct {
+	configPath string                // config file aliases and macros are saved to; empty without a home directory
+	recordDir  string                // record API responses and sprites to this fixtures directory
+	replayDir  string                // replay API responses and sprites from this fixtures directory
+	offline    bool                  // serve everything from the offline bundle
+	bundlePath string                // offline bundle location
+	output     commands.OutputFormat // how results are printed
+}
+
+// loadSettings reads ~/.pokedex_config.json and the environment, then applies command-line flags on top.
+// An unreadable config file or an invalid URL is fatal, so a mirror is never silently swapped for the public API.
+func loadSettings() (appconfig.Settings, options) {
+	path, err := appconfig.DefaultPath()
+	if err != nil {
+		path = ""
+	}
+
+	var commandNames []string
+	for name := range commands.GetCommands() {
+		commandNames = append(commandNames, name)
+	}
+	warn := func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: %v. It has been ignored.\n", err)
+	}
+	settings, err := appconfig.Load(path, appconfig.WithCommands(commandNames...), appconfig.WithWarnings(warn))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	opts := options{configPath: path}
+	flag.StringVar(&settings.BaseURL, "api-base-url", settings.BaseURL,
+		"PokeAPI base URL, e.g. http://localhost:8000/api/v2/ (env "+appconfig.EnvBaseURL+")")
+	flag.StringVar(&settings.SpritesBaseURL, "sprites-base-url", settings.SpritesBaseURL,
+		"mirror of the PokeAPI sprites repository (env "+appconfig.EnvSpritesBaseURL+")")
+	flag.StringVar(&opts.recordDir, "record", "", "record every API response and sprite to this fixtures directory")
+	flag.StringVar(&opts.replayDir, "replay", "", "serve API responses and sprites from this fixtures directory instead of the network")
+	flag.BoolVar(&opts.offline, "offline", false, "run without the network, reading everything from the offline bundle")
+	flag.StringVar(&opts.bundlePath, "bundle", "", "offline bundle to read or build (default ~/.pokedex_bundle.zip)")
+	flag.BoolVar(&settings.AutoCorrect, "autocorrect", settings.AutoCorrect,
+		"retry misspelt Pokemon and location area names with the closest match")
+	output := flag.String("output", string(commands.FormatText), "print results as text, json or yaml")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: pokedexcli [flags] [command [args...]]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Without a command, an interactive Pokedex session is started.\n\nFlags:\n")
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	for {
-		fmt.Print("pokedex > ")
-		scanner.Scan()
-		userInput := cleanInput(scanner.Text())
-		if len(userInput) == 0 {
-			continue
+	if opts.bundlePath == "" {
+		if path, err := bundle.DefaultPath(); err == nil {
+			opts.bundlePath = path
 		}
+	}
+
+	format, err := commands.ParseOutputFormat(*output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	opts.output = format
+
+	if err := settings.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if opts.recordDir != "" && opts.replayDir != "" {
+		fmt.Fprintln(os.Stderr, "Error: --record and --replay cannot be used together")
+		os.Exit(2)
+	}
+	if opts.offline && (opts.recordDir != "" || opts.replayDir != "") {
+		fmt.Fprintln(os.Stderr, "Error: --offline cannot be combined with --record or --replay")
+		os.Exit(2)
+	}
 
-		if len(userInput[0]) > maxCommandLength {
-			fmt.Println("Command too long")
-			continue
+	return settings, opts
+}
+
+// setupFixtures installs the record or replay transport requested on the command line.
+// Reports whether either mode is active.
+func setupFixtures(opts options) bool {
+	switch {
+	case opts.recordDir != "":
+		httputil.SetDefaultTransport(httputil.NewRecordTransport(opts.recordDir, nil))
+		fmt.Fprintf(os.Stderr, "Recording fixtures to %s\n", opts.recordDir)
+	case opts.replayDir != "":
+		httputil.SetDefaultTransport(httputil.NewReplayTransport(opts.replayDir))
+		fmt.Fprintf(os.Stderr, "Replaying fixtures from %s\n", opts.replayDir)
+	default:
+		return false
+	}
+	return true
+}
+
+// goOffline routes every request to the offline bundle. A missing or unreadable bundle is fatal,
+// since nothing would work without it.
+func goOffline(cfg *commands.Config) {
+	if cfg.BundlePath == "" {
+		fmt.Fprintln(cfg.Stderr(), "Error: no offline bundle location; use --bundle <path>")
+		os.Exit(2)
+	}
+
+	reader, err := bundle.Open(cfg.BundlePath)
+	if err != nil {
+		fmt.Fprintf(cfg.Stderr(), "Error: %v\n", err)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(cfg.Stderr(), "Build one first by running 'bundle build' while online.")
 		}
+		os.Exit(1)
+	}
+
+	httputil.SetOfflineSource(reader)
+	cfg.Offline = true
+	fmt.Fprintf(cfg.Stderr(), "Offline mode: %d responses from %s\n", reader.Len(), cfg.BundlePath)
+}
+
+// interruptHandler routes Ctrl-C to the command that is currently running.
+// While a command runs, Ctrl-C cancels its context so pending requests are aborted
+// and the prompt comes back. At the prompt the line editor handles Ctrl-C itself.
+type interruptHandler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc // nil while waiting at the prompt
+}
 
-		command := userInput[0]
+// listen starts handling interrupt signals for the rest of the program.
+func (h *interruptHandler) listen() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
 
-		if cmd, exists := commands.GetCommands()[command]; exists {
-			args := userInput[1:]
-			err := cmd.Callback(cfg, args...)
-			if err != nil {
-				fmt.Printf("Error executing command '%s': %v\n", command, err)
+	go func() {
+		for range signals {
+			h.mu.Lock()
+			cancel := h.cancel
+			h.mu.Unlock()
+
+			if cancel != nil {
+				cancel()
 			}
-		} else {
-			fmt.Printf("Unknown command\n")
 		}
+	}()
+}
+
+// run executes the named command with a context that is cancelled by Ctrl-C.
+func (h *interruptHandler) run(cfg *commands.Config, name string, args ...string) error {
+	return h.do(func(ctx context.Context) error {
+		return commands.Run(ctx, cfg, name, args...)
+	})
+}
+
+// do calls fn with a context that is cancelled by Ctrl-C.
+func (h *interruptHandler) do(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h.mu.Lock()
+	h.cancel = cancel
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		h.cancel = nil
+		h.mu.Unlock()
+		cancel()
+	}()
+
+	return fn(ctx)
+}
+
+// newCache creates the size-bounded API response cache, backed by ~/.pokedex_cache/ when useDisk is set and a home directory is available.
+// Expired entries are retained for a while so they can be served stale while being refreshed.
+func newCache(useDisk bool) *pokecache.Cache {
+	opts := []pokecache.Option{
+		pokecache.WithMaxBytes(cacheMaxBytes),
+		pokecache.WithStaleRetention(staleRetention),
+	}
+	if !useDisk {
+		return pokecache.NewCache(cacheTimeoutLength, opts...)
+	}
+	if diskDir, err := pokecache.DefaultDiskDir(); err == nil {
+		opts = append(opts, pokecache.WithDiskDir(diskDir))
+	}
+	return pokecache.NewCache(cacheTimeoutLength, opts...)
+}
+
+// loadSavedPokedex sets up the save file store and named slots, then restores previously caught Pokemon.
+// A corrupt save file is reported and the session starts with an empty Pokedex.
+// Any other failure disables saving so that an unreadable file is never overwritten.
+func loadSavedPokedex(cfg *commands.Config) {
+	path, err := savefile.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(cfg.Stderr(), "Warning: %v. Your Pokedex will not be saved this session.\n", err)
+		return
+	}
+	cfg.Store = savefile.NewStore(path)
+
+	if slotsDir, err := savefile.DefaultSlotsDir(); err == nil {
+		cfg.Slots = savefile.NewSlots(slotsDir)
+	}
+
+	err = commands.LoadPokedex(cfg)
+	var corruptErr *savefile.CorruptError
+	switch {
+	case err == nil:
+	case errors.As(err, &corruptErr):
+		fmt.Fprintf(cfg.Stderr(), "Warning: %v\nStarting with an empty Pokedex.\n", err)
+	default:
+		fmt.Fprintf(cfg.Stderr(), "Warning: %v\nYour Pokedex will not be saved this session.\n", err)
+		cfg.Store = nil
 	}
 }
 
 // cleanInput takes a raw text string and returns a cleaned slice of strings.
-// It converts the input to lowercase and splits it by whitespace.
+// It splits the input by whitespace, drops # comments and lowercases the command name;
+// commands.Run lowercases the arguments of commands that don't take file paths.
 // Returns a slice of strings where each element is a whitespace-separated word from the input.
 func cleanInput(text string) []string {
-	var input []string
-	input = strings.Fields(strings.ToLower(text))
-	return input
+	return commands.ParseLine(text)
 }
@@ -2,10 +2,28 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/kiefbc/pokedexcli/commands"
+	"github.com/kiefbc/pokedexcli/internal/appconfig"
+	"github.com/kiefbc/pokedexcli/internal/bundle"
+	"github.com/kiefbc/pokedexcli/internal/fakeapi"
+	"github.com/kiefbc/pokedexcli/internal/fuzzy"
+	"github.com/kiefbc/pokedexcli/internal/history"
+	"github.com/kiefbc/pokedexcli/internal/httputil"
 	"github.com/kiefbc/pokedexcli/internal/pokecache"
-	"io"
+	"github.com/kiefbc/pokedexcli/internal/savefile"
+	"github.com/kiefbc/pokedexcli/internal/sprites"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -14,6 +32,48 @@ const (
 	testCacheTimeout = 5 * time.Minute
 )
 
+// newTestCache creates a cache that is closed automatically when the test finishes,
+// so its background reaper does not outlive the test.
+func newTestCache(t *testing.T, opts ...pokecache.Option) *pokecache.Cache {
+	t.Helper()
+	cache := pokecache.NewCache(testCacheTimeout, opts...)
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+// fakeClock is a clock for pokecache.WithClock that only moves when advanced,
+// so tests can expire cache entries without sleeping.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newFakeAPI starts a fake PokeAPI that is shut down when the test finishes.
+// HOME is pointed at a temporary directory so downloaded sprites stay out of the real sprite cache.
+func newFakeAPI(t *testing.T) *fakeapi.Server {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	server := fakeapi.NewServer()
+	t.Cleanup(server.Close)
+	return server
+}
+
 // TestCleanInput tests the cleanInput function with various input scenarios.
 // It verifies that input is properly cleaned, lowercased, and split into fields.
 func TestCleanInput(t *testing.T) {
@@ -33,6 +93,15 @@ func TestCleanInput(t *testing.T) {
 			input:    "  hello, world!  how are you?  ",
 			expected: []string{"hello,", "world!", "how", "are", "you?"},
 		},
+		{
+			// Arguments are lowercased by commands.Run once aliases are resolved
+			input:    "Catch PIKACHU # the yellow one",
+			expected: []string{"catch", "PIKACHU"},
+		},
+		{
+			input:    "SOURCE Scripts/Hunt.pdx",
+			expected: []string{"source", "Scripts/Hunt.pdx"},
+		},
 		// add more cases here
 	}
 
@@ -87,22 +156,13 @@ func TestCommandExit(t *testing.T) {
 		commands.SetExiter(mockExiter)
 		defer commands.SetExiter(originalExiter)
 
-		// Capture stdout to get the actual output
-		old := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
+		var buf bytes.Buffer
 
 		// Call the actual CommandExit function
-		err := commands.CommandExit(&commands.Config{
-			Cache: pokecache.NewCache(testCacheTimeout),
+		err := commands.CommandExit(context.Background(), &commands.Config{
+			Out:   &buf,
+			Cache: newTestCache(t),
 		})
-		// Restore stdout
-		w.Close()
-		os.Stdout = old
-
-		// Read the captured output
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
 		actual := buf.String()
 
 		// Check for errors
@@ -125,55 +185,144 @@ func TestCommandExit(t *testing.T) {
 	}
 }
 
+// orderedExiter records the exit code in a shared log of events.
+type orderedExiter struct {
+	events *[]string
+}
+
+func (e orderedExiter) Exit(code int) {
+	*e.events = append(*e.events, fmt.Sprintf("exit %d", code))
+}
+
+// TestClosingExiter tests that the exit command closes the REPL's line editor, restoring
+// the terminal, before the process is ended.
+func TestClosingExiter(t *testing.T) {
+	var events []string
+	exiter := closingExiter{
+		close: func() error {
+			events = append(events, "close")
+			return nil
+		},
+		next: orderedExiter{events: &events},
+	}
+
+	originalExiter := commands.GetExiter()
+	commands.SetExiter(exiter)
+	defer commands.SetExiter(originalExiter)
+
+	cfg := &commands.Config{Out: &bytes.Buffer{}, Cache: newTestCache(t)}
+	if err := commands.CommandExit(context.Background(), cfg); err != nil {
+		t.Fatalf("CommandExit() returned an error: %v", err)
+	}
+
+	if expected := []string{"close", "exit 0"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("events = %q; want %q", events, expected)
+	}
+}
+
 // TestCommandHelp tests the CommandHelp function to verify it displays
 // the welcome message and all expected command information.
 func TestCommandHelp(t *testing.T) {
 	cases := []struct {
-		input            string
+		args             []string
 		expectedContains []string
+		expectedErr      string
 	}{
 		{
-			input: "help",
 			expectedContains: []string{
 				"Welcome to the Pokedex!",
 				"Usage:",
 				"help: Displays a help message",
 				"exit: Exit the Pokedex",
+				"Pokemon:\n  catch: Catch a specific Pokemon\n  inspect: View details of a caught Pokemon\n  pokedex: View all caught Pokemon\n",
+				"Aliases:\n  c = catch\n",
 			},
 		},
-		// add more cases
+		{
+			args: []string{"catch"},
+			expectedContains: []string{
+				"catch: Catch a specific Pokemon",
+				"Usage: catch <pokemon>",
+				"Arguments:\n  pokemon  a Pokemon name\n",
+				"Examples:\n  catch pikachu\n",
+				"Aliases: c",
+			},
+		},
+		{
+			args:             []string{"format"},
+			expectedContains: []string{"Usage: format [text|json|yaml]", "format  an output format (optional, one of text, json or yaml)"},
+		},
+		{
+			args:             []string{"c"},
+			expectedContains: []string{"c is an alias for catch", "Usage: catch <pokemon>"},
+		},
+		{
+			args:             []string{"empty"},
+			expectedContains: []string{"empty is an alias for \n"},
+		},
+		{
+			args:        []string{"ctach"},
+			expectedErr: `no command named "ctach" - did you mean catch?`,
+		},
 	}
 
 	for _, c := range cases {
-		old := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
-
-		err := commands.CommandHelp(&commands.Config{
-			Cache: pokecache.NewCache(testCacheTimeout),
-		})
-
-		w.Close()
-		os.Stdout = old
-
 		var buf bytes.Buffer
-		io.Copy(&buf, r)
+		err := commands.CommandHelp(context.Background(), &commands.Config{
+			Out:     &buf,
+			Cache:   newTestCache(t),
+			Aliases: map[string]string{"c": "catch", "empty": ""}, // a broken alias must not break help
+		}, c.args...)
 		actual := buf.String()
 
+		if c.expectedErr != "" {
+			if err == nil || err.Error() != c.expectedErr {
+				t.Errorf("commandHelp(%v) error = %v; want %q", c.args, err, c.expectedErr)
+			}
+			continue
+		}
 		if err != nil {
-			t.Errorf("commandHelp() returned an error: %v", err)
+			t.Errorf("commandHelp(%v) returned an error: %v", c.args, err)
 		}
 
 		for _, expected := range c.expectedContains {
 			if !bytes.Contains([]byte(actual), []byte(expected)) {
-				t.Errorf("commandHelp() output missing expected string: %q\nGot: %q", expected, actual)
+				t.Errorf("commandHelp(%v) output missing expected string: %q\nGot: %q", c.args, expected, actual)
+			}
+		}
+	}
+}
+
+// TestCheckArgs tests that Run checks arguments against the command's ArgSpecs before running it.
+func TestCheckArgs(t *testing.T) {
+	cases := []struct {
+		name        string
+		args        []string
+		expectedErr string
+	}{
+		{name: "catch", expectedErr: "catch command requires a Pokemon name (usage: catch <pokemon>)"},
+		{name: "map", args: []string{"kanto"}, expectedErr: "too many arguments for map (usage: map)"},
+		{name: "format", args: []string{"xml"}, expectedErr: `invalid format "xml" for format (expected text, json or yaml)`},
+		{name: "cache", args: []string{"explode"}, expectedErr: `invalid action "explode" for cache (expected stats, list, purge or ttl)`},
+		{name: "format", args: []string{"json"}},
+		{name: "macro", args: []string{"hunt", "=", "explore", "$1;", "catch", "$2"}},
+	}
+
+	for _, c := range cases {
+		cfg := &commands.Config{Cache: newTestCache(t), Pokedex: make(map[string]commands.Pokemon)}
+		_, err := captureOutput(cfg, func() error {
+			return commands.Run(context.Background(), cfg, c.name, c.args...)
+		})
+		if c.expectedErr == "" {
+			if err != nil {
+				t.Errorf("%s %v returned an error: %v", c.name, c.args, err)
 			}
+		} else if err == nil || err.Error() != c.expectedErr {
+			t.Errorf("%s %v error = %v; want %q", c.name, c.args, err, c.expectedErr)
 		}
 	}
 }
 
-// TestCommandGetMaps tests the CommandGetMaps function to verify it handles
-// API responses correctly and prints expected location area names.
 func TestCommandGetMaps(t *testing.T) {
 	cases := []struct {
 		name             string
@@ -191,28 +340,21 @@ func TestCommandGetMaps(t *testing.T) {
 		},
 	}
 
+	server := newFakeAPI(t)
+
 	for _, c := range cases {
 		// Create config
 		cfg := &commands.Config{
 			NextURL: c.initialNextURL,
-			Cache:   pokecache.NewCache(testCacheTimeout),
+			BaseURL: server.BaseURL(),
+			Cache:   newTestCache(t),
 		}
 
-		// Capture stdout
-		old := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
+		var buf bytes.Buffer
+		cfg.Out = &buf
 
 		// Call CommandGetMaps
-		err := commands.CommandGetMaps(cfg)
-
-		// Restore stdout
-		w.Close()
-		os.Stdout = old
-
-		// Read captured output
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
+		err := commands.CommandGetMaps(context.Background(), cfg)
 		actual := buf.String()
 
 		// Check for errors
@@ -239,7 +381,7 @@ func TestCommandGetMapsBack(t *testing.T) {
 	}{
 		{
 			name:               "previous page request",
-			initialPreviousURL: "https://pokeapi.co/api/v2/location-area/?offset=0&limit=20",
+			initialPreviousURL: "location-area/?offset=0&limit=20",
 			expectedContains: []string{
 				"canalave-city-area",
 				"eterna-city-area",
@@ -248,28 +390,21 @@ func TestCommandGetMapsBack(t *testing.T) {
 		},
 	}
 
+	server := newFakeAPI(t)
+
 	for _, c := range cases {
 		// Create config
 		cfg := &commands.Config{
-			PreviousURL: c.initialPreviousURL,
-			Cache:       pokecache.NewCache(testCacheTimeout),
+			PreviousURL: server.BaseURL() + c.initialPreviousURL,
+			BaseURL:     server.BaseURL(),
+			Cache:       newTestCache(t),
 		}
 
-		// Capture stdout
-		old := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
+		var buf bytes.Buffer
+		cfg.Out = &buf
 
 		// Call CommandGetMapsBack
-		err := commands.CommandGetMapsBack(cfg)
-
-		// Restore stdout
-		w.Close()
-		os.Stdout = old
-
-		// Read captured output
-		var buf bytes.Buffer
-		io.Copy(&buf, r)
+		err := commands.CommandGetMapsBack(context.Background(), cfg)
 		actual := buf.String()
 
 		// Check for errors
@@ -286,6 +421,36 @@ func TestCommandGetMapsBack(t *testing.T) {
 	}
 }
 
+// TestMapPagination tests that map and mapb walk forwards and backwards through
+// the location-area pages using the pagination links returned by the API.
+func TestMapPagination(t *testing.T) {
+	server := newFakeAPI(t)
+	cfg := &commands.Config{
+		BaseURL: server.BaseURL(),
+		Cache:   newTestCache(t),
+	}
+
+	steps := []struct {
+		command  func(context.Context, *commands.Config, ...string) error
+		contains string
+		excludes string
+	}{
+		{commands.CommandGetMaps, "canalave-city-area", "mt-coronet-1f-route-216"},
+		{commands.CommandGetMaps, "mt-coronet-1f-route-216", "canalave-city-area"},
+		{commands.CommandGetMapsBack, "canalave-city-area", "mt-coronet-1f-route-216"},
+	}
+
+	for i, step := range steps {
+		output, err := captureOutput(cfg, func() error { return step.command(context.Background(), cfg) })
+		if err != nil {
+			t.Fatalf("step %d returned an error: %v", i+1, err)
+		}
+		if !strings.Contains(output, step.contains) || strings.Contains(output, step.excludes) {
+			t.Errorf("step %d output = %q; want %q and not %q", i+1, output, step.contains, step.excludes)
+		}
+	}
+}
+
 // TestCommandExploreMap tests the CommandExploreMap function to verify it handles
 // different scenarios including missing arguments, valid location areas, and API responses.
 func TestCommandExploreMap(t *testing.T) {
@@ -309,8 +474,24 @@ func TestCommandExploreMap(t *testing.T) {
 			expectedContains: []string{
 				"Exploring canalave-city-area...",
 				"Found Pokemon:",
+				" - tentacool",
+				" - pikachu",
+			},
+		},
+		{
+			name:        "location with no Pokemon",
+			args:        []string{"sinnoh-pokemon-league-area"},
+			expectError: false,
+			expectedContains: []string{
+				"No Pokemon found in this area.",
 			},
 		},
+		{
+			name:          "unknown location area",
+			args:          []string{"nowhere-area"},
+			expectError:   true,
+			errorContains: "failed to explore nowhere-area",
+		},
 		{
 			name:        "location with uppercase converted to lowercase",
 			args:        []string{"CANALAVE-CITY-AREA"},
@@ -322,28 +503,21 @@ func TestCommandExploreMap(t *testing.T) {
 		},
 	}
 
+	server := newFakeAPI(t)
+
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			// Create config
 			cfg := &commands.Config{
-				Cache: pokecache.NewCache(testCacheTimeout),
+				BaseURL: server.BaseURL(),
+				Cache:   newTestCache(t),
 			}
 
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
+			var buf bytes.Buffer
+			cfg.Out = &buf
 
 			// Call CommandExploreMap
-			err := commands.CommandExploreMap(cfg, c.args...)
-
-			// Restore stdout
-			w.Close()
-			os.Stdout = old
-
-			// Read captured output
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
+			err := commands.CommandExploreMap(context.Background(), cfg, c.args...)
 			actual := buf.String()
 
 			// Check error expectation
@@ -411,29 +585,22 @@ func TestCommandCatchPokemon(t *testing.T) {
 		},
 	}
 
+	server := newFakeAPI(t)
+
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			// Create config with existing Pokedex
 			cfg := &commands.Config{
-				Cache:   pokecache.NewCache(testCacheTimeout),
+				BaseURL: server.BaseURL(),
+				Cache:   newTestCache(t),
 				Pokedex: c.existingPokedex,
 			}
 
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
+			var buf bytes.Buffer
+			cfg.Out = &buf
 
 			// Call CommandCatchPokemon
-			err := commands.CommandCatchPokemon(cfg, c.args...)
-
-			// Restore stdout
-			w.Close()
-			os.Stdout = old
-
-			// Read captured output
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
+			err := commands.CommandCatchPokemon(context.Background(), cfg, c.args...)
 			actual := buf.String()
 
 			// Check error expectation
@@ -477,11 +644,11 @@ func TestCommandInspect(t *testing.T) {
 			errorContains: "inspect command requires a Pokemon name",
 		},
 		{
-			name:             "pokemon not caught",
-			args:             []string{"pikachu"},
-			pokedex:          make(map[string]commands.Pokemon),
-			expectError:      false,
-			expectedContains: []string{"you have not caught that pokemon"},
+			name:          "pokemon not caught",
+			args:          []string{"pikachu"},
+			pokedex:       make(map[string]commands.Pokemon),
+			expectError:   true,
+			errorContains: `no caught Pokemon named "pikachu"`,
 		},
 		{
 			name: "valid pokemon inspection",
@@ -499,10 +666,12 @@ func TestCommandInspect(t *testing.T) {
 			},
 			expectError: false,
 			expectedContains: []string{
-				"Pikachu (#25)", // Name will be bold but (#25) part is not
-				"Height: 4 dm",
-				"Weight: 60 hg",
-				"Base Experience: 112",
+				"Pikachu", // Name and number are styled separately
+				"#25",
+				"0.4 m",  // Height is shown in metres
+				"6.0 kg", // Weight is shown in kilograms
+				"112",
+				"Base Experience",
 				"Electric", // Type will be colored, so just check for the type name
 			},
 		},
@@ -522,8 +691,9 @@ func TestCommandInspect(t *testing.T) {
 			},
 			expectError: false,
 			expectedContains: []string{
-				"Pikachu (#25)", // Name will be bold but (#25) part is not
-				"Height: 4 dm",
+				"Pikachu",
+				"#25",
+				"0.4 m",
 			},
 		},
 	}
@@ -532,25 +702,15 @@ func TestCommandInspect(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			// Create config
 			cfg := &commands.Config{
-				Cache:   pokecache.NewCache(testCacheTimeout),
+				Cache:   newTestCache(t),
 				Pokedex: c.pokedex,
 			}
 
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
+			var buf bytes.Buffer
+			cfg.Out = &buf
 
 			// Call CommandInspect
-			err := commands.CommandInspect(cfg, c.args...)
-
-			// Restore stdout
-			w.Close()
-			os.Stdout = old
-
-			// Read captured output
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
+			err := commands.CommandInspect(context.Background(), cfg, c.args...)
 			actual := buf.String()
 
 			// Check error expectation
@@ -619,25 +779,15 @@ func TestCommandPokedex(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			// Create config
 			cfg := &commands.Config{
-				Cache:   pokecache.NewCache(testCacheTimeout),
+				Cache:   newTestCache(t),
 				Pokedex: c.pokedex,
 			}
 
-			// Capture stdout
-			old := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
+			var buf bytes.Buffer
+			cfg.Out = &buf
 
 			// Call CommandPokedex
-			err := commands.CommandPokedex(cfg)
-
-			// Restore stdout
-			w.Close()
-			os.Stdout = old
-
-			// Read captured output
-			var buf bytes.Buffer
-			io.Copy(&buf, r)
+			err := commands.CommandPokedex(context.Background(), cfg)
 			actual := buf.String()
 
 			// Check for errors
@@ -654,3 +804,1700 @@ func TestCommandPokedex(t *testing.T) {
 		})
 	}
 }
+
+// TestPokedexPersistence tests that a saved Pokedex can be loaded back and that
+// corrupt save files are backed up rather than silently discarded.
+func TestPokedexPersistence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "save.json")
+
+	saved := &commands.Config{
+		Pokedex: map[string]commands.Pokemon{
+			"pikachu": {Name: "pikachu", Height: 4, Weight: 60, Types: []string{"electric"}, ID: 25},
+		},
+		Store: savefile.NewStore(path),
+	}
+	if err := commands.SavePokedex(saved); err != nil {
+		t.Fatalf("SavePokedex() returned an error: %v", err)
+	}
+
+	loaded := &commands.Config{Store: savefile.NewStore(path)}
+	if err := commands.LoadPokedex(loaded); err != nil {
+		t.Fatalf("LoadPokedex() returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Pokedex, saved.Pokedex) {
+		t.Errorf("LoadPokedex() = %v; want %v", loaded.Pokedex, saved.Pokedex)
+	}
+
+	// Truncate the file to simulate a crash mid-write by an older version
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, data[:len(data)/2], 0644)
+
+	corrupt := &commands.Config{Store: savefile.NewStore(path)}
+	err := commands.LoadPokedex(corrupt)
+	var corruptErr *savefile.CorruptError
+	if !errors.As(err, &corruptErr) {
+		t.Fatalf("LoadPokedex() error = %v; want *savefile.CorruptError", err)
+	}
+	if _, err := os.Stat(corruptErr.BackupPath); err != nil {
+		t.Errorf("expected backup at %s: %v", corruptErr.BackupPath, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected corrupt save file to be moved aside, stat error: %v", err)
+	}
+}
+
+// captureOutput runs fn with cfg's output going to a buffer and returns everything fn printed.
+func captureOutput(cfg *commands.Config, fn func() error) (string, error) {
+	var buf bytes.Buffer
+	old := cfg.Out
+	cfg.Out = &buf
+	defer func() { cfg.Out = old }()
+
+	err := fn()
+	return buf.String(), err
+}
+
+// TestSaveSlots tests saving, listing, loading and deleting named save slots.
+func TestSaveSlots(t *testing.T) {
+	dir := t.TempDir()
+	slots := savefile.NewSlots(dir)
+
+	cfg := &commands.Config{
+		NextURL: "https://pokeapi.co/api/v2/location-area/?offset=20&limit=20",
+		Pokedex: map[string]commands.Pokemon{
+			"pikachu": {Name: "pikachu", Height: 4, Weight: 60},
+			"zubat":   {Name: "zubat", Height: 8, Weight: 75},
+		},
+		Slots: slots,
+	}
+
+	if _, err := captureOutput(cfg, func() error { return commands.CommandSave(context.Background(), cfg, "ash") }); err != nil {
+		t.Fatalf("CommandSave() returned an error: %v", err)
+	}
+
+	actual, err := captureOutput(cfg, func() error { return commands.CommandSlots(context.Background(), cfg) })
+	if err != nil {
+		t.Fatalf("CommandSlots() returned an error: %v", err)
+	}
+	if !bytes.Contains([]byte(actual), []byte("ash: 2 Pokemon")) {
+		t.Errorf("CommandSlots() output missing slot listing\nGot: %q", actual)
+	}
+
+	// Loading a slot turns automatic saving off, so exiting leaves the autosave as it was
+	var stderr bytes.Buffer
+	autosave := savefile.NewStore(filepath.Join(t.TempDir(), "pokedex.json"))
+	restored := &commands.Config{Slots: slots, Store: autosave, Pokedex: map[string]commands.Pokemon{"budew": {Name: "budew"}}, Err: &stderr}
+	if err := commands.SavePokedex(restored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := captureOutput(restored, func() error { return commands.CommandLoad(context.Background(), restored, "ash") }); err != nil {
+		t.Fatalf("CommandLoad() returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Pokedex, cfg.Pokedex) || restored.NextURL != cfg.NextURL {
+		t.Errorf("CommandLoad() restored %+v; want %+v", restored, cfg)
+	}
+	if !strings.Contains(stderr.String(), "no longer saved automatically") {
+		t.Errorf("CommandLoad() stderr = %q; want a warning that autosave is off", stderr.String())
+	}
+	if err := commands.Shutdown(restored); err != nil {
+		t.Fatalf("Shutdown() returned an error: %v", err)
+	}
+	saved := &commands.Config{Store: autosave}
+	if err := commands.LoadPokedex(saved); err != nil || len(saved.Pokedex) != 1 {
+		t.Errorf("autosave after CommandLoad() and Shutdown() = %v, %v; want it unchanged with only budew", saved.Pokedex, err)
+	}
+
+	// Listing a corrupt slot reports it without moving the file aside
+	corrupt := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	actual, err = captureOutput(cfg, func() error { return commands.CommandSlots(context.Background(), cfg) })
+	if err != nil || !strings.Contains(actual, "broken (unreadable:") {
+		t.Errorf("CommandSlots() with a corrupt slot = %q, %v; want it listed as unreadable", actual, err)
+	}
+	if backups, _ := filepath.Glob(corrupt + ".corrupt-*"); len(backups) > 0 {
+		t.Errorf("CommandSlots() backed up the corrupt slot to %v; want it left in place", backups)
+	}
+	if _, err := os.Stat(corrupt); err != nil {
+		t.Errorf("CommandSlots() moved the corrupt slot: %v", err)
+	}
+
+	if _, err := captureOutput(cfg, func() error { return commands.CommandDeleteSlot(context.Background(), cfg, "ash") }); err != nil {
+		t.Fatalf("CommandDeleteSlot() returned an error: %v", err)
+	}
+	if _, err := captureOutput(restored, func() error { return commands.CommandLoad(context.Background(), restored, "ash") }); err == nil {
+		t.Errorf("expected CommandLoad() of a deleted slot to fail")
+	}
+	if _, err := captureOutput(cfg, func() error { return commands.CommandSave(context.Background(), cfg, "../escape") }); err == nil {
+		t.Errorf("expected CommandSave() to reject a path traversal slot name")
+	}
+}
+
+// TestCacheDiskTier tests that entries written to the disk tier survive into a
+// new cache instance and that the TTL is honoured when reading them back.
+func TestCacheDiskTier(t *testing.T) {
+	dir := t.TempDir()
+	key := "https://pokeapi.co/api/v2/pokemon/pikachu"
+	val := []byte(`{"name":"pikachu"}`)
+
+	first := newTestCache(t, pokecache.WithDiskDir(dir))
+	if err := first.Add(key, val); err != nil {
+		t.Fatalf("Add() returned an error: %v", err)
+	}
+
+	// A fresh cache simulates a restart or a second REPL process
+	second := newTestCache(t, pokecache.WithDiskDir(dir))
+	got, found := second.Get(key)
+	if !found || !bytes.Equal(got, val) {
+		t.Errorf("Get() from disk tier = %q, %v; want %q, true", got, found, val)
+	}
+
+	expired := pokecache.NewCache(time.Nanosecond, pokecache.WithDiskDir(dir))
+	defer expired.Close()
+	if _, found := expired.Get(key); found {
+		t.Errorf("Get() returned an entry older than the TTL")
+	}
+}
+
+// TestCacheEviction tests that the least recently used entries are evicted once
+// the cache exceeds its entry budget.
+func TestCacheEviction(t *testing.T) {
+	cache := newTestCache(t, pokecache.WithMaxEntries(2))
+
+	cache.Add("a", []byte("1"))
+	cache.Add("b", []byte("2"))
+	cache.Get("a") // "b" is now the least recently used
+	cache.Add("c", []byte("3"))
+
+	if _, found := cache.Get("b"); found {
+		t.Errorf("expected least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("expected entry %q to remain cached", key)
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Bytes != 2 || stats.Evictions != 1 {
+		t.Errorf("Stats() = %+v; want 2 entries, 2 bytes, 1 eviction", stats)
+	}
+}
+
+// TestCommandCache tests the cache command's stats, list, purge and ttl subcommands.
+func TestCommandCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cache := newTestCache(t)
+	cache.Add("https://pokeapi.co/api/v2/pokemon/pikachu", []byte(`{"name":"pikachu"}`))
+	cache.Add("https://pokeapi.co/api/v2/location-area/", []byte(`{"count":0}`))
+	cache.Get("https://pokeapi.co/api/v2/pokemon/pikachu")
+	cache.Get("https://pokeapi.co/api/v2/pokemon/zubat")
+
+	cfg := &commands.Config{Cache: cache}
+
+	cases := []struct {
+		name             string
+		args             []string
+		expectError      bool
+		expectedContains []string
+	}{
+		{
+			name:             "stats",
+			args:             []string{"stats"},
+			expectedContains: []string{"Entries:     2", "Hits:        1", "Misses:      1", "reaped every 1m15s", "Sprites:     0"},
+		},
+		{
+			name:             "list",
+			args:             []string{"list"},
+			expectedContains: []string{"pokemon/pikachu", "location-area/", "2 entries"},
+		},
+		{
+			name:             "set ttl",
+			args:             []string{"ttl", "10m"},
+			expectedContains: []string{"Cache TTL set to 10m0s"},
+		},
+		{
+			name:        "invalid ttl",
+			args:        []string{"ttl", "soon"},
+			expectError: true,
+		},
+		{
+			name:             "purge by prefix",
+			args:             []string{"purge", "https://pokeapi.co/api/v2/pokemon/"},
+			expectedContains: []string{"Purged 1 cached responses"},
+		},
+		{
+			name:        "unknown subcommand",
+			args:        []string{"explode"},
+			expectError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := captureOutput(cfg, func() error { return commands.CommandCache(context.Background(), cfg, c.args...) })
+			if c.expectError != (err != nil) {
+				t.Errorf("CommandCache(%v) error = %v; expectError %v", c.args, err, c.expectError)
+			}
+			for _, expected := range c.expectedContains {
+				if !bytes.Contains([]byte(actual), []byte(expected)) {
+					t.Errorf("CommandCache(%v) output missing expected string: %q\nGot: %q", c.args, expected, actual)
+				}
+			}
+		})
+	}
+
+	if cache.TTL() != 10*time.Minute {
+		t.Errorf("TTL() = %s; want 10m0s", cache.TTL())
+	}
+	if interval := cache.Stats().ReapInterval; interval != 150*time.Second {
+		t.Errorf("ReapInterval after SetTTL() = %s; want 2m30s", interval)
+	}
+	if _, found := cache.Get("https://pokeapi.co/api/v2/location-area/"); !found {
+		t.Errorf("purge by prefix removed a non-matching entry")
+	}
+}
+
+// TestCacheExpiry tests that Cache.Get never serves entries older than the TTL,
+// while GetStale still returns them inside the stale retention window.
+func TestCacheExpiry(t *testing.T) {
+	clock := newFakeClock()
+	ttl := time.Minute
+	cache := newTestCache(t, pokecache.WithClock(clock.Now), pokecache.WithStaleRetention(time.Hour))
+	cache.SetTTL(ttl)
+	cache.Add("key", []byte("value"))
+
+	clock.Advance(ttl / 2)
+	if _, age, found := cache.GetWithAge("key"); !found || age != ttl/2 {
+		t.Errorf("GetWithAge() = age %s, found %v; want fresh entry aged %s", age, found, ttl/2)
+	}
+
+	clock.Advance(ttl)
+
+	if _, found := cache.Get("key"); found {
+		t.Errorf("Get() served an expired entry")
+	}
+	if _, age, found := cache.GetStale("key"); !found || age <= ttl {
+		t.Errorf("GetStale() = age %s, found %v; want stale entry", age, found)
+	}
+}
+
+// TestStaleWhileRevalidate tests that httputil.GetResponse returns stale data
+// immediately and refreshes it in the background.
+func TestStaleWhileRevalidate(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		fmt.Fprintf(w, `{"count":%d}`, n)
+	}))
+	defer server.Close()
+
+	httputil.SetStaleWhileRevalidate(true)
+	defer httputil.SetStaleWhileRevalidate(false)
+
+	clock := newFakeClock()
+	cache := newTestCache(t, pokecache.WithClock(clock.Now), pokecache.WithStaleRetention(time.Hour))
+
+	first, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache)
+	if err != nil || first.Count != 1 {
+		t.Fatalf("first GetResponse() = %+v, %v; want count 1", first, err)
+	}
+
+	clock.Advance(2 * testCacheTimeout)
+
+	stale, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache)
+	if err != nil || stale.Count != 1 {
+		t.Fatalf("stale GetResponse() = %+v, %v; want stale count 1", stale, err)
+	}
+
+	httputil.Wait(time.Minute)
+
+	refreshed, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache)
+	if err != nil || refreshed.Count != 2 {
+		t.Errorf("refreshed GetResponse() = %+v, %v; want count 2", refreshed, err)
+	}
+}
+
+// TestWaitCancelsSlowRevalidation tests that httputil.Wait gives up on a background
+// refresh that outlasts its timeout instead of blocking until the request fails.
+func TestWaitCancelsSlowRevalidation(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			<-r.Context().Done() // the refresh never gets an answer
+			return
+		}
+		fmt.Fprint(w, `{"count":1}`)
+	}))
+	defer server.Close()
+
+	httputil.SetStaleWhileRevalidate(true)
+	defer httputil.SetStaleWhileRevalidate(false)
+
+	clock := newFakeClock()
+	cache := newTestCache(t, pokecache.WithClock(clock.Now), pokecache.WithStaleRetention(time.Hour))
+	if _, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache); err != nil {
+		t.Fatalf("first GetResponse() returned an error: %v", err)
+	}
+	clock.Advance(2 * testCacheTimeout)
+	if _, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache); err != nil {
+		t.Fatalf("stale GetResponse() returned an error: %v", err)
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		httputil.Wait(10 * time.Millisecond)
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait() blocked on a refresh that outlasted its timeout")
+	}
+
+	if stale, _, found := cache.GetStale(server.URL); !found || string(stale) != `{"count":1}` {
+		t.Errorf("GetStale() after a cancelled refresh = %q, %v; want the stale entry kept", stale, found)
+	}
+}
+
+// TestCacheClose tests that closing a cache, or cancelling its context, stops
+// the background reaper goroutine.
+func TestCacheClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	closed := pokecache.NewCache(testCacheTimeout, pokecache.WithContext(ctx))
+	cancelled := pokecache.NewCache(testCacheTimeout, pokecache.WithContext(ctx))
+	defer cancelled.Close()
+
+	select {
+	case <-closed.Done():
+		t.Fatal("reaper stopped before Close() was called")
+	default:
+	}
+
+	closed.Close()
+	closed.Close() // closing twice must be safe
+	select {
+	case <-closed.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("reaper still running after Close()")
+	}
+
+	cancel()
+	select {
+	case <-cancelled.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("reaper still running after its context was cancelled")
+	}
+}
+
+// TestGetResponseCoalescesRequests tests that concurrent GetResponse calls for the
+// same URL share a single HTTP request and all receive the same result.
+func TestGetResponseCoalescesRequests(t *testing.T) {
+	var requests atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(arrived)
+		}
+		<-release
+		fmt.Fprint(w, `{"count":42}`)
+	}))
+	defer server.Close()
+
+	cache := newTestCache(t)
+
+	const callers = 10
+	results := make(chan commands.AreaMaps, callers)
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			result, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache)
+			results <- result
+			errs <- err
+		}()
+	}
+
+	// Callers that start before the response is cached join the in-flight request,
+	// and the rest are served from the cache, so either way only one request is made
+	<-arrived
+	close(release)
+
+	for i := 0; i < callers; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("GetResponse() returned an error: %v", err)
+		}
+		if result := <-results; result.Count != 42 {
+			t.Errorf("GetResponse() count = %d; want 42", result.Count)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("server received %d requests; want 1", n)
+	}
+}
+
+// TestGetResponseRetries tests that transient failures are retried with Retry-After
+// honoured, and that permanent failures report the number of attempts made.
+func TestGetResponseRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case requests.Add(1) == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case requests.Load() == 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, `{"count":3}`)
+		}
+	}))
+	defer server.Close()
+
+	httputil.SetRetryPolicy(httputil.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	defer httputil.SetRetryPolicy(httputil.DefaultRetryPolicy)
+
+	result, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, newTestCache(t))
+	if err != nil || result.Count != 3 {
+		t.Errorf("GetResponse() = %+v, %v; want count 3 after retries", result, err)
+	}
+
+	_, err = httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL+"/missing", newTestCache(t))
+	if err == nil || !bytes.Contains([]byte(err.Error()), []byte("after 1 attempt")) {
+		t.Errorf("GetResponse() error = %v; want a 404 that is not retried", err)
+	}
+}
+
+// TestRateLimiter tests that requests are spaced out by the shared rate limiter
+// and that a caller waiting for the limiter can give up via its context.
+func TestRateLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	httputil.SetRateLimit(50, 1)
+	defer httputil.SetRateLimit(httputil.DefaultRequestsPerSecond, httputil.DefaultBurst)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := httputil.Fetch(context.Background(), server.URL, nil); err != nil {
+			t.Fatalf("Fetch() returned an error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("5 requests at 50/s took %s; want at least 80ms of limiting", elapsed)
+	}
+
+	// The bucket is empty after the loop, so the next token is two seconds away
+	httputil.SetRateLimit(0.5, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := httputil.Fetch(ctx, server.URL, nil); err == nil {
+		t.Errorf("Fetch() succeeded; want an error once the limiter wait exceeds the deadline")
+	}
+}
+
+// TestGetResponseCancellation tests that cancelling a caller's context returns straight
+// away without failing other callers that share the same in-flight request.
+func TestGetResponseCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"count":7}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cache := newTestCache(t)
+
+	done := make(chan error, 1)
+	go func() {
+		result, err := httputil.GetResponseWithDefault[commands.AreaMaps](context.Background(), server.URL, cache)
+		if err == nil && result.Count != 7 {
+			err = fmt.Errorf("count = %d; want 7", result.Count)
+		}
+		done <- err
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := httputil.GetResponseWithDefault[commands.AreaMaps](ctx, server.URL, cache)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetResponse() error = %v; want context.Canceled", err)
+	}
+
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Errorf("uncancelled GetResponse() failed: %v", err)
+	}
+	if _, found := cache.Get(server.URL); !found {
+		t.Errorf("response was not cached after the cancelled caller gave up")
+	}
+}
+
+// TestGetResponseAbortsAbandonedRequest tests that the HTTP request is aborted once every
+// caller waiting for it has been cancelled, rather than running on in the background.
+func TestGetResponseAbortsAbandonedRequest(t *testing.T) {
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := httputil.GetResponseWithDefault[commands.AreaMaps](ctx, server.URL, newTestCache(t))
+		done <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("GetResponse() error = %v; want context.Canceled", err)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server never saw the abandoned request aborted")
+	}
+}
+
+// TestConfigurableBaseURL tests that commands fetch from the configured API base URL
+// and that sprite downloads are redirected to the configured sprites mirror.
+func TestConfigurableBaseURL(t *testing.T) {
+	server := newFakeAPI(t)
+
+	cfg := &commands.Config{
+		BaseURL:        strings.TrimSuffix(server.BaseURL(), "/"), // no trailing slash on purpose
+		SpritesBaseURL: server.SpritesBaseURL(),
+		Cache:          newTestCache(t),
+		Pokedex: map[string]commands.Pokemon{
+			"pikachu": {
+				Name:           "pikachu",
+				SpriteOfficial: commands.DefaultSpritesBaseURL + "sprites/pokemon/other/official-artwork/25.png",
+			},
+		},
+	}
+
+	output, err := captureOutput(cfg, func() error { return commands.CommandGetMaps(context.Background(), cfg) })
+	if err != nil || !strings.Contains(output, "canalave-city-area") {
+		t.Errorf("CommandGetMaps() = %q, %v; want the first page from the mirror", output, err)
+	}
+	if _, err := captureOutput(cfg, func() error {
+		return commands.CommandExploreMap(context.Background(), cfg, "canalave-city-area")
+	}); err != nil {
+		t.Errorf("CommandExploreMap() returned an error: %v", err)
+	}
+	if _, err := captureOutput(cfg, func() error { return commands.CommandInspect(context.Background(), cfg, "pikachu") }); err != nil {
+		t.Errorf("CommandInspect() returned an error: %v", err)
+	}
+
+	want := []string{
+		"/api/v2/location-area/",
+		"/api/v2/location-area/canalave-city-area",
+		"/sprites/sprites/pokemon/other/official-artwork/25.png",
+	}
+	if got := server.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("mirror received %v; want %v", got, want)
+	}
+}
+
+// TestAppConfigLoad tests that environment variables override the config file
+// and that invalid URLs are rejected.
+func TestAppConfigLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"base_url":"http://file.example/api/v2/","sprites_base_url":"http://file.example/sprites/"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(appconfig.EnvBaseURL, "http://env.example/api/v2/")
+	settings, err := appconfig.Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	want := appconfig.Settings{BaseURL: "http://env.example/api/v2/", SpritesBaseURL: "http://file.example/sprites/"}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Load() = %+v; want %+v", settings, want)
+	}
+
+	if _, err := appconfig.Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Load() of a missing file returned an error: %v", err)
+	}
+
+	// Aliases and macros must run a command, and with WithCommands a known one.
+	// Invalid ones are dropped with a warning, keeping the rest of the file
+	definitions := []struct {
+		config           string
+		expectedWarnings []string
+		expectedAliases  int
+		expectedMacros   int
+	}{
+		{config: `{"aliases":{"x":""}}`, expectedWarnings: []string{`alias "x" has no command`}},
+		{config: `{"aliases":{"x":"# nothing"}}`, expectedWarnings: []string{`alias "x" has no command`}},
+		{config: `{"macros":{"hunt":" ; "}}`, expectedWarnings: []string{`macro "hunt" has no command`}},
+		{config: `{"aliases":{"x":"fly"}}`, expectedWarnings: []string{`alias "x" runs unknown command "fly"`}},
+		{config: `{"macros":{"hunt":"explore $1; fly"}}`, expectedWarnings: []string{`macro "hunt" runs unknown command "fly"`}},
+		{
+			config:           `{"aliases":{"c":"catch","f":"fly","ff":"f high"}}`,
+			expectedWarnings: []string{`alias "f" runs unknown command "fly"`, `alias "ff" runs unknown command "f"`},
+			expectedAliases:  1,
+		},
+		{config: `{"aliases":{"c":"catch","cc":"c pikachu"},"macros":{"hunt":"explore $1; cc"}}`, expectedAliases: 2, expectedMacros: 1},
+	}
+	for _, d := range definitions {
+		if err := os.WriteFile(path, []byte(`{"sprites_base_url":"http://localhost:8000/sprites/",`+d.config[1:]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var warnings []string
+		settings, err := appconfig.Load(path, appconfig.WithCommands("catch", "explore"),
+			appconfig.WithWarnings(func(err error) { warnings = append(warnings, err.Error()) }))
+		if err != nil {
+			t.Fatalf("Load(%s) returned an error: %v", d.config, err)
+		}
+		if settings.SpritesBaseURL != "http://localhost:8000/sprites/" {
+			t.Errorf("Load(%s) SpritesBaseURL = %q; want the rest of the file loaded", d.config, settings.SpritesBaseURL)
+		}
+		if len(settings.Aliases) != d.expectedAliases || len(settings.Macros) != d.expectedMacros {
+			t.Errorf("Load(%s) kept %v and %v; want %d aliases and %d macros", d.config, settings.Aliases, settings.Macros, d.expectedAliases, d.expectedMacros)
+		}
+		if len(warnings) != len(d.expectedWarnings) {
+			t.Errorf("Load(%s) warnings = %q; want %q", d.config, warnings, d.expectedWarnings)
+			continue
+		}
+		for i, expected := range d.expectedWarnings {
+			if !strings.Contains(warnings[i], expected) {
+				t.Errorf("Load(%s) warning %d = %q; want it to contain %q", d.config, i, warnings[i], expected)
+			}
+		}
+	}
+
+	for _, bad := range []string{"ftp://example.com/", "localhost:8000", "/api/v2/"} {
+		if err := (appconfig.Settings{BaseURL: bad}).Validate(); err == nil {
+			t.Errorf("Validate() accepted base URL %q", bad)
+		}
+	}
+}
+
+// TestRecordReplay tests that a session recorded against the API can be replayed
+// without it, and that replaying an unrecorded URL fails loudly.
+func TestRecordReplay(t *testing.T) {
+	server := newFakeAPI(t)
+	dir := t.TempDir()
+
+	sprites.SetCacheEnabled(false)
+	defer sprites.SetCacheEnabled(true)
+	defer httputil.SetDefaultTransport(nil)
+
+	newConfig := func() *commands.Config {
+		return &commands.Config{
+			BaseURL:        server.BaseURL(),
+			SpritesBaseURL: server.SpritesBaseURL(),
+			Cache:          newTestCache(t),
+			Pokedex: map[string]commands.Pokemon{
+				"pikachu": {
+					Name:           "pikachu",
+					ID:             25,
+					Types:          []string{"electric"},
+					SpriteOfficial: commands.DefaultSpritesBaseURL + "sprites/pokemon/other/official-artwork/25.png",
+				},
+			},
+		}
+	}
+	session := func(cfg *commands.Config) string {
+		output, err := captureOutput(cfg, func() error {
+			if err := commands.CommandExploreMap(context.Background(), cfg, "canalave-city-area"); err != nil {
+				return err
+			}
+			return commands.CommandInspect(context.Background(), cfg, "pikachu")
+		})
+		if err != nil {
+			t.Fatalf("session returned an error: %v", err)
+		}
+		return output
+	}
+
+	httputil.SetDefaultTransport(httputil.NewRecordTransport(dir, nil))
+	recorded := session(newConfig())
+
+	host := strings.ReplaceAll(strings.TrimPrefix(server.URL, "http://"), ":", "_")
+	for _, file := range []string{"manifest.json", host + "/sprites/sprites/pokemon/other/official-artwork/25.png"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("recording is missing %s: %v", file, err)
+		}
+	}
+
+	// Replay must not need the server at all
+	server.Close()
+	httputil.SetDefaultTransport(httputil.NewReplayTransport(dir))
+
+	if replayed := session(newConfig()); replayed != recorded {
+		t.Errorf("replayed session differs from the recording:\n%s\nwant:\n%s", replayed, recorded)
+	}
+
+	cfg := newConfig()
+	_, err := captureOutput(cfg, func() error {
+		return commands.CommandExploreMap(context.Background(), cfg, "eterna-city-area")
+	})
+	if !errors.Is(err, httputil.ErrNotRecorded) {
+		t.Errorf("CommandExploreMap() error = %v; want ErrNotRecorded for an unrecorded URL", err)
+	}
+}
+
+// TestOfflineBundle tests that 'bundle build' captures everything the commands need
+// and that an offline session served from the bundle works without the API.
+func TestOfflineBundle(t *testing.T) {
+	server := newFakeAPI(t)
+	bundlePath := filepath.Join(t.TempDir(), "bundle.zip")
+
+	sprites.SetCacheEnabled(false)
+	defer sprites.SetCacheEnabled(true)
+	httputil.SetRateLimit(0, 0)
+	defer httputil.SetRateLimit(httputil.DefaultRequestsPerSecond, httputil.DefaultBurst)
+
+	online := &commands.Config{
+		BaseURL:        server.BaseURL(),
+		SpritesBaseURL: server.SpritesBaseURL(),
+		Cache:          newTestCache(t),
+		Pokedex:        make(map[string]commands.Pokemon),
+		BundlePath:     bundlePath,
+	}
+	output, err := captureOutput(online, func() error { return commands.CommandBundle(context.Background(), online, "build") })
+	if err != nil {
+		t.Fatalf("bundle build returned an error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Saved") || strings.Contains(output, "Fetching") {
+		t.Errorf("bundle build output = %q; want a summary without progress lines", output)
+	}
+
+	// From here on the API is gone
+	server.Close()
+
+	reader, err := bundle.Open(bundlePath)
+	if err != nil {
+		t.Fatalf("bundle.Open() returned an error: %v", err)
+	}
+	defer reader.Close()
+	if _, err := reader.Get(server.BaseURL() + "pokemon-species/25/"); err != nil {
+		t.Errorf("bundle is missing pikachu's species: %v", err)
+	}
+
+	httputil.SetOfflineSource(reader)
+	defer httputil.SetOfflineSource(nil)
+
+	offline := &commands.Config{
+		BaseURL:        server.BaseURL(),
+		SpritesBaseURL: server.SpritesBaseURL(),
+		Cache:          newTestCache(t),
+		Pokedex:        make(map[string]commands.Pokemon),
+		BundlePath:     bundlePath,
+		Offline:        true,
+	}
+	session := []struct {
+		command func(context.Context, *commands.Config, ...string) error
+		args    []string
+	}{
+		{commands.CommandGetMaps, nil},
+		{commands.CommandGetMaps, nil},
+		{commands.CommandGetMapsBack, nil},
+		{commands.CommandExploreMap, []string{"oreburgh-mine-1f"}},
+		{commands.CommandCatchPokemon, []string{"zubat"}},
+	}
+	for _, step := range session {
+		if _, err := captureOutput(offline, func() error { return step.command(context.Background(), offline, step.args...) }); err != nil {
+			t.Errorf("offline command %v returned an error: %v", step.args, err)
+		}
+	}
+
+	offline.Pokedex["geodude"] = commands.Pokemon{
+		Name:           "geodude",
+		SpriteOfficial: commands.DefaultSpritesBaseURL + "sprites/pokemon/other/official-artwork/74.png",
+	}
+	if _, err := reader.Get(server.SpritesBaseURL() + "sprites/pokemon/other/official-artwork/74.png"); err != nil {
+		t.Errorf("bundle is missing geodude's sprite: %v", err)
+	}
+	if _, err := captureOutput(offline, func() error { return commands.CommandInspect(context.Background(), offline, "geodude") }); err != nil {
+		t.Errorf("offline inspect returned an error: %v", err)
+	}
+
+	// Completion and suggestions use the name indexes from the bundle
+	if actual := commands.Complete(context.Background(), offline, "catch bu"); !reflect.DeepEqual(actual, []string{"budew", "bulbasaur"}) {
+		t.Errorf("offline Complete(\"catch bu\") = %q; want the Pokemon index from the bundle", actual)
+	}
+	if actual := commands.Complete(context.Background(), offline, "explore eterna-f"); !reflect.DeepEqual(actual, []string{"eterna-forest-area"}) {
+		t.Errorf("offline Complete(\"explore eterna-f\") = %q; want the area index from the bundle", actual)
+	}
+
+	_, err = captureOutput(offline, func() error { return commands.CommandCatchPokemon(context.Background(), offline, "bulbasuar") })
+	var unknownErr *commands.UnknownNameError
+	if !errors.As(err, &unknownErr) || !strings.Contains(err.Error(), "did you mean bulbasaur?") {
+		t.Errorf("offline catch of a misspelt Pokemon: error = %v; want a suggestion from the bundle", err)
+	}
+
+	_, err = captureOutput(offline, func() error { return commands.CommandExploreMap(context.Background(), offline, "great-marsh-area-1") })
+	if !errors.Is(err, bundle.ErrNotInBundle) {
+		t.Errorf("exploring an area missing from the bundle: error = %v; want ErrNotInBundle", err)
+	}
+
+	if _, err := captureOutput(offline, func() error { return commands.CommandBundle(context.Background(), offline, "build") }); err == nil {
+		t.Errorf("bundle build succeeded in offline mode; want an error")
+	}
+}
+
+// TestRunOnce tests one-shot mode: only command output on stdout and an exit code
+// that reflects whether the command succeeded.
+func TestRunOnce(t *testing.T) {
+	server := newFakeAPI(t)
+
+	cases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{
+			name:         "successful command",
+			args:         []string{"explore", "eterna-city-area"},
+			expectedCode: exitOK,
+			expectedOut:  "Exploring eterna-city-area...\nFound Pokemon:\n - budew\n",
+		},
+		{
+			name:         "arguments are normalised like REPL input",
+			args:         []string{"EXPLORE", "Eterna-City-Area"},
+			expectedCode: exitOK,
+			expectedOut:  "Exploring eterna-city-area...\nFound Pokemon:\n - budew\n",
+		},
+		{
+			name:         "failing command",
+			args:         []string{"explore", "nowhere-area"},
+			expectedCode: exitError,
+		},
+		{
+			name:         "inspecting an uncaught Pokemon",
+			args:         []string{"inspect", "missingno"},
+			expectedCode: exitError,
+		},
+		{
+			name:         "missing argument",
+			args:         []string{"catch"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "too many arguments",
+			args:         []string{"pokedex", "all"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "invalid argument value",
+			args:         []string{"format", "xml"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "unknown command",
+			args:         []string{"fly", "cerulean"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "run without a script",
+			args:         []string{"run"},
+			expectedCode: exitUsage,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &commands.Config{
+				BaseURL: server.BaseURL(),
+				Cache:   newTestCache(t),
+				Pokedex: make(map[string]commands.Pokemon),
+			}
+
+			var code int
+			output, _ := captureOutput(cfg, func() error {
+				code = runOnce(&interruptHandler{}, cfg, c.args)
+				return nil
+			})
+
+			if code != c.expectedCode {
+				t.Errorf("runOnce(%v) = %d; want %d", c.args, code, c.expectedCode)
+			}
+			if output != c.expectedOut {
+				t.Errorf("runOnce(%v) printed %q; want %q", c.args, output, c.expectedOut)
+			}
+		})
+	}
+}
+
+// TestRunScript tests script execution: comments, carrying on after errors by default,
+// stopping at the first error after 'set -e', and the run and source entry points.
+func TestRunScript(t *testing.T) {
+	server := newFakeAPI(t)
+	dir := t.TempDir()
+
+	cases := []struct {
+		name             string
+		script           string
+		expectedContains []string
+		expectedMissing  []string
+		expectedStderr   string
+		errorContains    string
+	}{
+		{
+			name: "comments and blank lines are skipped",
+			script: `# Look around Eterna City
+explore eterna-city-area   # budew lives here
+
+pokedex
+`,
+			expectedContains: []string{" - budew", "Your Pokedex is empty."},
+		},
+		{
+			name: "errors are reported and the script carries on",
+			script: `explore nowhere-area
+explore eterna-city-area
+`,
+			expectedContains: []string{" - budew"},
+			expectedStderr:   "Error: " + filepath.Join(dir, "Hunt.pdx") + ":1: explore: failed to explore nowhere-area",
+			errorContains:    "1 command(s) in",
+		},
+		{
+			name: "set -e stops at the first error",
+			script: `set -e
+explore nowhere-area
+explore eterna-city-area
+`,
+			expectedMissing: []string{" - budew"},
+			errorContains:   "Hunt.pdx:2: explore: failed to explore nowhere-area",
+		},
+		{
+			name:          "unsupported set option",
+			script:        "set -x\n",
+			errorContains: "Hunt.pdx:1: unsupported option",
+		},
+		{
+			name:          "scripts cannot source themselves forever",
+			script:        "set -e\nsource " + filepath.Join(dir, "Hunt.pdx") + "\n",
+			errorContains: "scripts nested too deeply",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Mixed case in the path checks that file names are not lowercased
+			path := filepath.Join(dir, "Hunt.pdx")
+			if err := os.WriteFile(path, []byte(c.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var stderr bytes.Buffer
+			cfg := &commands.Config{
+				BaseURL: server.BaseURL(),
+				Cache:   newTestCache(t),
+				Pokedex: make(map[string]commands.Pokemon),
+				Err:     &stderr,
+			}
+
+			input := cleanInput("source " + path)
+			output, err := captureOutput(cfg, func() error {
+				return commands.Run(context.Background(), cfg, input[0], input[1:]...)
+			})
+
+			if c.errorContains == "" && err != nil {
+				t.Errorf("source returned an error: %v", err)
+			}
+			if c.errorContains != "" && (err == nil || !strings.Contains(err.Error(), c.errorContains)) {
+				t.Errorf("source error = %v; want it to contain %q", err, c.errorContains)
+			}
+			for _, expected := range c.expectedContains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("output missing %q\nGot: %q", expected, output)
+				}
+			}
+			for _, missing := range c.expectedMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("output unexpectedly contains %q\nGot: %q", missing, output)
+				}
+			}
+			if !strings.Contains(stderr.String(), c.expectedStderr) {
+				t.Errorf("stderr = %q; want it to contain %q", stderr.String(), c.expectedStderr)
+			}
+		})
+	}
+
+	// The same script through 'pokedexcli run <file>' and piped into stdin
+	path := filepath.Join(dir, "Batch.pdx")
+	if err := os.WriteFile(path, []byte("set -e\nexplore eterna-city-area\nexplore nowhere-area\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newConfig := func() *commands.Config {
+		return &commands.Config{BaseURL: server.BaseURL(), Cache: newTestCache(t), Pokedex: make(map[string]commands.Pokemon)}
+	}
+
+	var code int
+	cfg := newConfig()
+	output, _ := captureOutput(cfg, func() error {
+		code = runOnce(&interruptHandler{}, cfg, []string{"run", path})
+		return nil
+	})
+	if code != exitError || !strings.Contains(output, " - budew") {
+		t.Errorf("run %s = %d, %q; want exit code %d after the output of the first command", path, code, output, exitError)
+	}
+
+	// A usage error inside the script fails the script, not the run command
+	if err := os.WriteFile(path, []byte("set -e\nfly cerulean\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg = newConfig()
+	captureOutput(cfg, func() error {
+		code = runOnce(&interruptHandler{}, cfg, []string{"run", path})
+		return nil
+	})
+	if code != exitError {
+		t.Errorf("run %s with an unknown command = %d; want exit code %d", path, code, exitError)
+	}
+
+	cfg = newConfig()
+	output, _ = captureOutput(cfg, func() error {
+		code = runBatch(&interruptHandler{}, cfg, strings.NewReader("# piped\nexplore eterna-city-area\n"))
+		return nil
+	})
+	if code != exitOK || output != "Exploring eterna-city-area...\nFound Pokemon:\n - budew\n" {
+		t.Errorf("runBatch() = %d, %q; want exit code 0 and only command output, no prompts", code, output)
+	}
+}
+
+// TestOutputFormats tests that map, explore, pokedex, inspect and catch print structured
+// results in JSON and YAML mode, and that the format command switches between modes.
+func TestOutputFormats(t *testing.T) {
+	server := newFakeAPI(t)
+
+	pikachu := commands.Pokemon{Name: "pikachu", Height: 4, Weight: 60, Types: []string{"electric"}, ID: 25}
+	newConfig := func(format commands.OutputFormat) *commands.Config {
+		return &commands.Config{
+			BaseURL: server.BaseURL(),
+			Cache:   newTestCache(t),
+			Pokedex: map[string]commands.Pokemon{"pikachu": pikachu},
+			Output:  format,
+		}
+	}
+	run := func(cfg *commands.Config, name string, args ...string) string {
+		t.Helper()
+		output, err := captureOutput(cfg, func() error {
+			return commands.Run(context.Background(), cfg, name, args...)
+		})
+		if err != nil {
+			t.Fatalf("%s returned an error: %v", name, err)
+		}
+		return output
+	}
+
+	var areaMaps commands.AreaMaps
+	if err := json.Unmarshal([]byte(run(newConfig(commands.FormatJSON), "map")), &areaMaps); err != nil {
+		t.Fatalf("map output is not JSON: %v", err)
+	}
+	if areaMaps.Count != 25 || len(areaMaps.Results) != 20 || areaMaps.Results[0].Name != "canalave-city-area" {
+		t.Errorf("map = %+v; want the first page of 20 of 25 areas", areaMaps)
+	}
+
+	var explored commands.ExploreResult
+	if err := json.Unmarshal([]byte(run(newConfig(commands.FormatJSON), "explore", "eterna-city-area")), &explored); err != nil {
+		t.Fatalf("explore output is not JSON: %v", err)
+	}
+	if explored.Area != "eterna-city-area" || len(explored.PokemonEncounters) != 1 || explored.PokemonEncounters[0].Pokemon.Name != "budew" {
+		t.Errorf("explore = %+v; want eterna-city-area with budew", explored)
+	}
+
+	var pokedex []commands.Pokemon
+	if err := json.Unmarshal([]byte(run(newConfig(commands.FormatJSON), "pokedex")), &pokedex); err != nil {
+		t.Fatalf("pokedex output is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(pokedex, []commands.Pokemon{pikachu}) {
+		t.Errorf("pokedex = %+v; want [%+v]", pokedex, pikachu)
+	}
+
+	var inspected commands.Pokemon
+	if err := json.Unmarshal([]byte(run(newConfig(commands.FormatJSON), "inspect", "pikachu")), &inspected); err != nil {
+		t.Fatalf("inspect output is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(inspected, pikachu) {
+		t.Errorf("inspect = %+v; want %+v", inspected, pikachu)
+	}
+	if err := commands.Run(context.Background(), newConfig(commands.FormatJSON), "inspect", "zubat"); err == nil {
+		t.Error("inspect of an uncaught Pokemon in JSON mode should return an error")
+	}
+
+	// Whether the catch succeeds is random, but the result is always a single JSON document
+	var caught commands.CatchResult
+	if err := json.Unmarshal([]byte(run(newConfig(commands.FormatJSON), "catch", "zubat")), &caught); err != nil {
+		t.Fatalf("catch output is not JSON: %v", err)
+	}
+	if caught.Name != "zubat" || caught.Caught != (caught.Pokemon != nil) {
+		t.Errorf("catch = %+v; want zubat with its details only if caught", caught)
+	}
+	if err := json.Unmarshal([]byte(run(newConfig(commands.FormatJSON), "catch", "pikachu")), &caught); err != nil || !caught.AlreadyCaught {
+		t.Errorf("catch of a Pokemon in the Pokedex = %+v, %v; want already_caught", caught, err)
+	}
+
+	// YAML uses the same keys as JSON
+	output := run(newConfig(commands.FormatYAML), "explore", "eterna-city-area")
+	expected := "area: eterna-city-area\npokemon_encounters:\n  - pokemon:\n      name: budew\n"
+	if !strings.HasPrefix(output, expected) {
+		t.Errorf("explore in YAML = %q; want it to start with %q", output, expected)
+	}
+
+	cfg := newConfig("")
+	run(cfg, "format", "yaml")
+	if cfg.Output != commands.FormatYAML {
+		t.Errorf("format yaml set Output to %q", cfg.Output)
+	}
+	run(cfg, "format", "text")
+	if output := run(cfg, "pokedex"); output != "Your Pokedex:\n  - pikachu\n" {
+		t.Errorf("pokedex after 'format text' = %q", output)
+	}
+	if _, err := captureOutput(cfg, func() error { return commands.Run(context.Background(), cfg, "format", "xml") }); err == nil {
+		t.Error("format xml should return an error")
+	}
+}
+
+// TestHistory tests that commands from concurrent sessions are merged into one history file,
+// that each session notices the other's commands, and that the file is trimmed to its limit.
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".pokedex_history")
+	first := history.New(path, 5)
+	second := history.New(path, 5)
+
+	if entries, err := first.Load(); err != nil || len(entries) != 0 {
+		t.Fatalf("Load() of a missing file = %v, %v; want an empty history", entries, err)
+	}
+	if first.Changed() {
+		t.Error("Changed() = true before anything was written")
+	}
+
+	for _, add := range []struct {
+		file *history.File
+		line string
+	}{
+		{first, "map"},
+		{second, "explore eterna-city-area"},
+		{first, "   "},
+		{first, "catch budew"},
+	} {
+		if err := add.file.Add(add.line); err != nil {
+			t.Fatalf("Add(%q) returned an error: %v", add.line, err)
+		}
+	}
+
+	if !first.Changed() {
+		t.Error("Changed() = false after another session added a command")
+	}
+	entries, err := first.Load()
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	expected := []string{"map", "explore eterna-city-area", "catch budew"}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Load() = %q; want %q", entries, expected)
+	}
+	if first.Changed() {
+		t.Error("Changed() = true right after Load()")
+	}
+
+	for _, line := range []string{"pokedex", "inspect budew", "help"} {
+		if err := second.Add(line); err != nil {
+			t.Fatalf("Add(%q) returned an error: %v", line, err)
+		}
+	}
+	entries, err = first.Load()
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	expected = []string{"explore eterna-city-area", "catch budew", "pokedex", "inspect budew", "help"}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Load() past the limit = %q; want the newest %q", entries, expected)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "\n") != 5 {
+		t.Errorf("history file was not trimmed to 5 commands:\n%s", data)
+	}
+}
+
+// TestComplete tests tab completion of command names, location areas, Pokemon and caught Pokemon.
+func TestComplete(t *testing.T) {
+	server := newFakeAPI(t)
+
+	cases := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{
+			name:     "command names",
+			line:     "ex",
+			expected: []string{"exit", "explore"},
+		},
+		{
+			name:     "command names are matched ignoring case",
+			line:     "POKE",
+			expected: []string{"pokedex"},
+		},
+		{
+			name:     "location areas from the index",
+			line:     "explore eter",
+			expected: []string{"eterna-city-area", "eterna-forest-area"},
+		},
+		{
+			name:     "Pokemon from the index",
+			line:     "catch bu",
+			expected: []string{"budew", "bulbasaur"},
+		},
+		{
+			name:     "caught Pokemon",
+			line:     "inspect ",
+			expected: []string{"pikachu", "zubat"},
+		},
+		{
+			name: "only one argument is completed",
+			line: "catch budew ",
+		},
+		{
+			name: "arguments of other commands",
+			line: "pokedex ",
+		},
+		{
+			name:     "fixed values",
+			line:     "format j",
+			expected: []string{"json"},
+		},
+		{
+			name:     "command names for help",
+			line:     "help ca",
+			expected: []string{"cache", "catch"},
+		},
+		{
+			name:     "arguments after an alias",
+			line:     "c bu",
+			expected: []string{"budew", "bulbasaur"},
+		},
+		{
+			name:     "aliases",
+			line:     "unalias ",
+			expected: []string{"c", "empty"},
+		},
+		{
+			name: "arguments after an empty alias",
+			line: "empty bu",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &commands.Config{
+				BaseURL: server.BaseURL(),
+				Cache:   newTestCache(t),
+				Pokedex: map[string]commands.Pokemon{"zubat": {Name: "zubat"}, "pikachu": {Name: "pikachu"}},
+				Aliases: map[string]string{"c": "catch", "empty": ""},
+			}
+
+			actual := commands.Complete(context.Background(), cfg, c.line)
+			if !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("Complete(%q) = %q; want %q", c.line, actual, c.expected)
+			}
+		})
+	}
+
+	// Without the index, names seen in command results are still completed
+	cfg := &commands.Config{BaseURL: server.BaseURL(), Cache: newTestCache(t), Pokedex: make(map[string]commands.Pokemon)}
+	if _, err := captureOutput(cfg, func() error {
+		return commands.CommandExploreMap(context.Background(), cfg, "eterna-city-area")
+	}); err != nil {
+		t.Fatalf("CommandExploreMap() returned an error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if actual := commands.Complete(ctx, cfg, "catch b"); !reflect.DeepEqual(actual, []string{"budew"}) {
+		t.Errorf("Complete(\"catch b\") without the index = %q; want the Pokemon seen in eterna-city-area", actual)
+	}
+	if actual := commands.Complete(ctx, cfg, "explore e"); !reflect.DeepEqual(actual, []string{"eterna-city-area"}) {
+		t.Errorf("Complete(\"explore e\") without the index = %q; want the explored area", actual)
+	}
+}
+
+// TestDidYouMean tests the edit distance used for suggestions, and that misspelt names
+// in catch, explore and inspect suggest the closest known names or, with auto-correct,
+// are replaced by the single best match.
+func TestDidYouMean(t *testing.T) {
+	distances := []struct {
+		a, b     string
+		expected int
+	}{
+		{"pikachu", "pikachu", 0},
+		{"pikachuu", "pikachu", 1},
+		{"pikahcu", "pikachu", 1}, // swapped letters count once
+		{"pkchu", "pikachu", 2},
+		{"", "zubat", 5},
+	}
+	for _, d := range distances {
+		if actual := fuzzy.Distance(d.a, d.b); actual != d.expected {
+			t.Errorf("Distance(%q, %q) = %d; want %d", d.a, d.b, actual, d.expected)
+		}
+	}
+
+	server := newFakeAPI(t)
+
+	cases := []struct {
+		name             string
+		command          string
+		args             []string
+		autoCorrect      bool
+		expectedErr      string
+		expectedContains []string
+		expectedStderr   string
+	}{
+		{
+			name:        "misspelt Pokemon",
+			command:     "catch",
+			args:        []string{"pikachuu"},
+			expectedErr: `no Pokemon named "pikachuu" - did you mean pikachu?`,
+		},
+		{
+			name:        "shortened location area",
+			command:     "explore",
+			args:        []string{"eterna-city"},
+			expectedErr: "did you mean eterna-city-area?",
+		},
+		{
+			name:        "nothing close",
+			command:     "catch",
+			args:        []string{"missingno"},
+			expectedErr: `no Pokemon named "missingno"`,
+		},
+		{
+			name:             "auto-correct catches the closest Pokemon",
+			command:          "catch",
+			args:             []string{"pikahcu"},
+			autoCorrect:      true,
+			expectedContains: []string{"Throwing a Pokeball at pikahcu..."},
+			expectedStderr:   "No Pokemon named \"pikahcu\", using pikachu\n",
+		},
+		{
+			name:             "auto-correct explores the closest area",
+			command:          "explore",
+			args:             []string{"eterna-city"},
+			autoCorrect:      true,
+			expectedContains: []string{"Exploring eterna-city-area...", " - budew"},
+			expectedStderr:   "No location area named \"eterna-city\", using eterna-city-area\n",
+		},
+		{
+			name:        "misspelt caught Pokemon",
+			command:     "inspect",
+			args:        []string{"zubta"},
+			expectedErr: `no caught Pokemon named "zubta" - did you mean zubat?`,
+		},
+		{
+			name:             "auto-correct inspects the closest caught Pokemon",
+			command:          "inspect",
+			args:             []string{"zubta"},
+			autoCorrect:      true,
+			expectedContains: []string{"Zubat"},
+			expectedStderr:   "No caught Pokemon named \"zubta\", using zubat\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			cfg := &commands.Config{
+				BaseURL:     server.BaseURL(),
+				Cache:       newTestCache(t),
+				Pokedex:     map[string]commands.Pokemon{"zubat": {Name: "zubat", Height: 8, Weight: 75}},
+				AutoCorrect: c.autoCorrect,
+				Err:         &stderr,
+			}
+
+			output, err := captureOutput(cfg, func() error {
+				return commands.Run(context.Background(), cfg, c.command, c.args...)
+			})
+			if c.expectedErr != "" {
+				var unknownErr *commands.UnknownNameError
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) || !errors.As(err, &unknownErr) {
+					t.Fatalf("%s %v returned %v; want an UnknownNameError containing %q", c.command, c.args, err, c.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s %v returned an error: %v", c.command, c.args, err)
+			}
+			for _, expected := range c.expectedContains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("output %q does not contain %q", output, expected)
+				}
+			}
+			if stderr.String() != c.expectedStderr {
+				t.Errorf("stderr = %q; want %q", stderr.String(), c.expectedStderr)
+			}
+		})
+	}
+}
+
+// TestAPIErrors tests that failed requests return typed errors carrying the URL and
+// status, and that commands describe them in friendly terms.
+func TestAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/slow":
+			<-r.Context().Done() // never answer before the client gives up
+		default:
+			fmt.Fprint(w, `{"count":`)
+		}
+	}))
+	defer server.Close()
+
+	httputil.SetRetryPolicy(httputil.RetryPolicy{MaxAttempts: 1})
+	defer httputil.SetRetryPolicy(httputil.DefaultRetryPolicy)
+
+	cases := []struct {
+		name            string
+		path            string
+		check           func(err error) (url string, status int, ok bool)
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name: "not found",
+			path: "/missing",
+			check: func(err error) (string, int, bool) {
+				var e *httputil.NotFoundError
+				if !errors.As(err, &e) {
+					return "", 0, false
+				}
+				return e.URL, e.StatusCode, true
+			},
+			expectedStatus:  http.StatusNotFound,
+			expectedMessage: "PokeAPI has nothing at " + server.URL + "/missing",
+		},
+		{
+			name: "rate limited",
+			path: "/busy",
+			check: func(err error) (string, int, bool) {
+				var e *httputil.RateLimitedError
+				if !errors.As(err, &e) {
+					return "", 0, false
+				}
+				return e.URL, e.StatusCode, true
+			},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedMessage: "PokeAPI is limiting requests, wait a moment and try again",
+		},
+		{
+			name: "server error",
+			path: "/down",
+			check: func(err error) (string, int, bool) {
+				var e *httputil.ServerError
+				if !errors.As(err, &e) {
+					return "", 0, false
+				}
+				return e.URL, e.StatusCode, true
+			},
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedMessage: "PokeAPI is unavailable (503 Service Unavailable), try again later",
+		},
+		{
+			name: "other status",
+			path: "/forbidden",
+			check: func(err error) (string, int, bool) {
+				var e *httputil.StatusError
+				if !errors.As(err, &e) {
+					return "", 0, false
+				}
+				return e.URL, e.StatusCode, true
+			},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "PokeAPI refused the request for " + server.URL + "/forbidden (403 Forbidden)",
+		},
+		{
+			name: "invalid JSON",
+			path: "/broken",
+			check: func(err error) (string, int, bool) {
+				var e *httputil.DecodeError
+				if !errors.As(err, &e) {
+					return "", 0, false
+				}
+				return e.URL, 0, true // the status is always 200, so it is not recorded
+			},
+			expectedMessage: "PokeAPI sent a response that could not be read for " + server.URL + "/broken",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			url := server.URL + c.path
+			_, err := commands.GetResponse[commands.AreaMaps](context.Background(), url, newTestCache(t))
+			if err == nil {
+				t.Fatal("GetResponse() returned no error")
+			}
+			if err.Error() != c.expectedMessage {
+				t.Errorf("error message = %q; want %q", err.Error(), c.expectedMessage)
+			}
+
+			errURL, status, ok := c.check(err)
+			if !ok {
+				t.Fatalf("errors.As(%v) found no %s error", err, c.name)
+			}
+			if errURL != url || status != c.expectedStatus {
+				t.Errorf("error URL and status = %s, %d; want %s, %d", errURL, status, url, c.expectedStatus)
+			}
+		})
+	}
+
+	client := httputil.NewClient(20 * time.Millisecond)
+	_, err := httputil.GetResponse[commands.AreaMaps](context.Background(), server.URL+"/slow", newTestCache(t), client)
+	var timeoutErr *httputil.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.URL != server.URL+"/slow" {
+		t.Errorf("GetResponse() of a slow URL = %v; want a TimeoutError", err)
+	}
+}
+
+// TestAliasesAndMacros tests defining, running and removing aliases and macros,
+// and that they are saved to the config file and listed by help.
+func TestAliasesAndMacros(t *testing.T) {
+	server := newFakeAPI(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"auto_correct":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &commands.Config{
+		BaseURL:    server.BaseURL(),
+		Cache:      newTestCache(t),
+		Pokedex:    make(map[string]commands.Pokemon),
+		ConfigPath: path,
+	}
+	run := func(line string) (string, error) {
+		words := commands.ParseLine(line)
+		return captureOutput(cfg, func() error {
+			return commands.Run(context.Background(), cfg, words[0], words[1:]...)
+		})
+	}
+
+	cases := []struct {
+		line             string
+		expectedContains []string
+		expectedErr      string
+	}{
+		{line: "alias c catch", expectedContains: []string{"c is now an alias for catch"}},
+		{line: "c pikachu", expectedContains: []string{"Throwing a Pokeball at pikachu..."}},
+		{line: "alias e explore eterna-city-area", expectedContains: []string{"e is now an alias for explore eterna-city-area"}},
+		{line: "e", expectedContains: []string{"Exploring eterna-city-area..."}},
+		{line: "macro hunt = explore $1; c $2", expectedContains: []string{"hunt now runs: explore $1; c $2"}},
+		{line: "hunt eterna-city-area budew", expectedContains: []string{"Exploring eterna-city-area...", "Throwing a Pokeball at budew..."}},
+		{line: "hunt eterna-city-area", expectedErr: "macro hunt needs 2 argument(s), got 1"},
+		{line: "hunt nowhere-area budew", expectedErr: "hunt step 1 (explore)"},
+		{line: "alias catch explore", expectedErr: `"catch" is already a command`},
+		{line: "alias x fly", expectedErr: `unknown command in "fly"`},
+		{line: "macro loop = loop", expectedErr: `unknown command in "loop"`},
+		{line: "alias", expectedContains: []string{"Aliases:\n  c = catch\n  e = explore eterna-city-area\n"}},
+		{line: "help", expectedContains: []string{"Aliases:\n  c = catch\n", "Macros:\n  hunt = explore $1; c $2\n"}},
+		{line: "unalias e", expectedContains: []string{"Removed e"}},
+		{line: "unalias e", expectedErr: `no alias or macro named "e"`},
+	}
+
+	for _, c := range cases {
+		output, err := run(c.line)
+		if c.expectedErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+				t.Errorf("%q returned %v; want an error containing %q", c.line, err, c.expectedErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q returned an error: %v", c.line, err)
+			continue
+		}
+		for _, expected := range c.expectedContains {
+			if !strings.Contains(output, expected) {
+				t.Errorf("%q output %q does not contain %q", c.line, output, expected)
+			}
+		}
+	}
+
+	// An alias of source keeps the case of the file name
+	script := filepath.Join(t.TempDir(), "Hunt.pdx")
+	if err := os.WriteFile(script, []byte("pokedex\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("alias s source"); err != nil {
+		t.Fatalf("alias s source returned an error: %v", err)
+	}
+	if output, err := run("s " + script); err != nil || !strings.Contains(output, "Your Pokedex") {
+		t.Errorf("s %s = %q, %v; want the script to run", script, output, err)
+	}
+	if _, err := run("unalias s"); err != nil {
+		t.Fatalf("unalias s returned an error: %v", err)
+	}
+
+	// So does a file name inside an alias or macro definition, while the names are folded
+	if _, err := run("alias Safari source " + script); err != nil {
+		t.Fatalf("alias Safari returned an error: %v", err)
+	}
+	if _, err := run("macro Safaris = source " + script + "; pokedex"); err != nil {
+		t.Fatalf("macro Safaris returned an error: %v", err)
+	}
+	if cfg.Aliases["safari"] != "source "+script || cfg.Macros["safaris"] != "source "+script+"; pokedex" {
+		t.Errorf("definitions = %q, %q; want the script path unchanged", cfg.Aliases["safari"], cfg.Macros["safaris"])
+	}
+	for _, name := range []string{"safari", "safaris"} {
+		if output, err := run(name); err != nil || !strings.Contains(output, "Your Pokedex") {
+			t.Errorf("%s = %q, %v; want the script to run", name, output, err)
+		}
+		if _, err := run("unalias " + name); err != nil {
+			t.Fatalf("unalias %s returned an error: %v", name, err)
+		}
+	}
+
+	// An empty alias, say from a hand-edited config file, fails instead of panicking
+	cfg.Aliases["empty"] = ""
+	if _, err := run("empty"); err == nil || err.Error() != "alias empty has no command" {
+		t.Errorf("an empty alias returned %v; want an error", err)
+	}
+	delete(cfg.Aliases, "empty")
+
+	// A macro that runs itself stops instead of recursing forever
+	cfg.Macros["again"] = "again"
+	if _, err := run("again"); err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Errorf("a macro running itself returned %v; want a nesting error", err)
+	}
+	delete(cfg.Macros, "again")
+
+	// Definitions are saved without touching other settings
+	settings, err := appconfig.Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	want := appconfig.Settings{
+		AutoCorrect: true,
+		Aliases:     map[string]string{"c": "catch"},
+		Macros:      map[string]string{"hunt": "explore $1; c $2"},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("saved settings = %+v; want %+v", settings, want)
+	}
+}
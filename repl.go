@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiefbc/pokedexcli/commands"
+	"github.com/kiefbc/pokedexcli/internal/history"
+	"github.com/peterh/liner"
+)
+
+const (
+	prompt = "pokedex > "
+
+	// completionTimeout bounds how long Tab waits for a name index to download
+	completionTimeout = 3 * time.Second
+)
+
+// runREPL reads commands from the terminal and runs them until the exit command or Ctrl-D.
+//
+// Lines are edited with liner: arrow keys move through the line and recall earlier commands,
+// and Ctrl-R searches them. Every command is added to ~/.pokedex_history straight away, and
+// commands typed in other sessions meanwhile are picked up before each prompt.
+// The line editor puts the terminal in raw mode, so it is closed before the exit command
+// ends the process as well as when runREPL returns.
+func runREPL(interrupts *interruptHandler, cfg *commands.Config) {
+	line := liner.NewLiner()
+	closeLine := sync.OnceValue(line.Close)
+	defer closeLine()
+
+	exiter := commands.GetExiter()
+	commands.SetExiter(closingExiter{close: closeLine, next: exiter})
+	defer commands.SetExiter(exiter)
+
+	line.SetCtrlCAborts(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetWordCompleter(completer(cfg))
+
+	hist := openHistory(cfg)
+	for {
+		if hist != nil && hist.Changed() {
+			if err := loadHistory(line, hist); err != nil {
+				fmt.Fprintf(cfg.Stderr(), "Warning: %v. Command history will not be saved.\n", err)
+				hist = nil
+			}
+		}
+
+		text, err := line.Prompt(prompt)
+		switch {
+		case err == nil:
+		case errors.Is(err, liner.ErrPromptAborted):
+			fmt.Fprintln(cfg.Stdout(), "(use 'exit' or Ctrl-D to quit)")
+			continue
+		case errors.Is(err, io.EOF):
+			// Ctrl-D ends the session just like the exit command
+			fmt.Fprintln(cfg.Stdout())
+			interrupts.run(cfg, "exit")
+			return
+		default:
+			fmt.Fprintf(cfg.Stderr(), "Error reading input: %v\n", err)
+			interrupts.run(cfg, "exit")
+			return
+		}
+
+		userInput := cleanInput(text)
+		if len(userInput) == 0 {
+			continue
+		}
+
+		line.AppendHistory(text)
+		if hist != nil {
+			if err := hist.Add(text); err != nil {
+				fmt.Fprintf(cfg.Stderr(), "Warning: %v\n", err)
+			}
+		}
+
+		if len(userInput[0]) > maxCommandLength {
+			fmt.Fprintln(cfg.Stdout(), "Command too long")
+			continue
+		}
+
+		command := userInput[0]
+		err = interrupts.run(cfg, command, userInput[1:]...)
+		switch {
+		case err == nil:
+		case errors.Is(err, commands.ErrUnknownCommand):
+			fmt.Fprintf(cfg.Stdout(), "Unknown command\n")
+		case errors.Is(err, context.Canceled):
+			fmt.Fprintln(cfg.Stdout(), "Command cancelled")
+		default:
+			fmt.Fprintf(cfg.Stdout(), "Error executing command '%s': %v\n", command, err)
+		}
+	}
+}
+
+// closingExiter closes the line editor before handing over to the next Exiter.
+// os.Exit skips deferred calls, so without it the terminal would be left in raw mode.
+type closingExiter struct {
+	close func() error
+	next  commands.Exiter
+}
+
+func (e closingExiter) Exit(code int) {
+	e.close()
+	e.next.Exit(code)
+}
+
+// completer returns the Tab handler for the line editor, which completes the word
+// before the cursor using commands.Complete.
+func completer(cfg *commands.Config) liner.WordCompleter {
+	return func(line string, pos int) (string, []string, string) {
+		start := strings.LastIndexAny(line[:pos], " \t") + 1
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+
+		completions := commands.Complete(ctx, cfg, line[:pos])
+		if len(completions) == 1 {
+			// A unique completion is finished, ready for the next word
+			completions[0] += " "
+		}
+		return line[:start], completions, line[pos:]
+	}
+}
+
+// openHistory returns the history file, or nil if there is no home directory to keep it in.
+func openHistory(cfg *commands.Config) *history.File {
+	path, err := history.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(cfg.Stderr(), "Warning: %v. Command history will not be saved.\n", err)
+		return nil
+	}
+	return history.New(path, history.DefaultMaxEntries)
+}
+
+// loadHistory replaces the editor's history with the contents of the history file.
+func loadHistory(line *liner.State, hist *history.File) error {
+	entries, err := hist.Load()
+	if err != nil {
+		return err
+	}
+
+	line.ClearHistory()
+	for _, entry := range entries {
+		line.AppendHistory(entry)
+	}
+	return nil
+}